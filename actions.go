@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// ActionRequest is a signed request from a player to resign, offer a draw
+// or chat. Signed is a signature of actionMessage for the request.
+type ActionRequest struct {
+	Side   string `json:"side"`
+	Signed string `json:"signed"`
+	Text   string `json:"text,omitempty"`
+}
+
+var resultWhiteWon = "WHITE_WON"
+var resultBlackWon = "BLACK_WON"
+var resultDraw = "DRAW"
+
+var maxChatLength = 500
+
+var errGameOver = newAPIError(http.StatusConflict, "game_over", "The game is already over.")
+var errTimeOut = newAPIError(http.StatusConflict, "time_out", "The game ended on time.")
+
+// actionMessage is what a player signs to resign, offer a draw, chat or
+// authenticate a socket. Resigning and offering a draw include the current
+// ply so an old signature can't be replayed later in the game.
+func actionMessage(action string, gameID uuid.UUID, ply int, text string) []byte {
+	if action == "chat" || action == "auth" {
+		return []byte(action + ":" + gameID.String() + ":" + text)
+	}
+	return []byte(action + ":" + gameID.String() + ":" + strconv.Itoa(ply))
+}
+
+func winner(side string) string {
+	if side == "WHITE" {
+		return resultWhiteWon
+	}
+	return resultBlackWon
+}
+
+// verifyAction checks that an action was signed by the player on its side.
+func verifyAction(actor *gameActor, action string, request ActionRequest) *APIError {
+	if request.Side != "WHITE" && request.Side != "BLACK" {
+		return newAPIError(http.StatusBadRequest, "invalid_side", "Side must be WHITE or BLACK.")
+	}
+	playerKey := sideKey(actor.game, request.Side)
+	if len(playerKey) == 0 {
+		return newAPIError(http.StatusConflict, "player_missing", "There is no "+strings.ToLower(request.Side)+" player.")
+	}
+	sig, err := hex.DecodeString(request.Signed)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, "invalid_signature_encoding", "Signature is not valid hex string.")
+	}
+	if !ed25519.Verify(playerKey, actionMessage(action, actor.game.GameID, actor.history.lastPly, request.Text), sig) {
+		return newAPIError(http.StatusUnauthorized, "invalid_signature", "Signature didn't verify properly.")
+	}
+	return nil
+}
+
+// finishGame records the result of a game and tells everyone watching.
+// Side is the player whose move or action ended the game. It returns false
+// if another instance changed the game first.
+func finishGame(actor *gameActor, side string, result string, reason string) bool {
+	game := &actor.game
+	previous := *game
+	game.Result = result
+	game.ResultReason = reason
+	game.DrawOffer = ""
+	if !saveGame(actor) {
+		*game = previous
+		return false
+	}
+	fmt.Println("Game " + game.GameID.String() + " finished: " + result + " by " + reason)
+	broadcast(actor, eventGameOver, GameOverPayload{
+		Result: result,
+		Reason: reason,
+		Side:   side,
+	})
+	return true
+}
+
+func resign(actor *gameActor, request ActionRequest) (Game, *APIError) {
+	if actor.game.Result != "" {
+		return Game{}, errGameOver
+	}
+	apiErr := verifyAction(actor, "resign", request)
+	if apiErr != nil {
+		return Game{}, apiErr
+	}
+	if !finishGame(actor, request.Side, winner(nextMoveAuthor(BoardState{MoveAuthor: request.Side})), "resignation") {
+		actor.conflict = true
+		return Game{}, errConflict
+	}
+	return actor.game, nil
+}
+
+// offerDraw offers a draw, or accepts one if the other side has already
+// offered. An offer stands until the other side moves.
+func offerDraw(actor *gameActor, request ActionRequest) (Game, *APIError) {
+	game := &actor.game
+	if game.Result != "" {
+		return Game{}, errGameOver
+	}
+	apiErr := verifyAction(actor, "draw", request)
+	if apiErr != nil {
+		return Game{}, apiErr
+	}
+
+	if game.DrawOffer == request.Side {
+		return Game{}, newAPIError(http.StatusConflict, "draw_already_offered", "You have already offered a draw.")
+	}
+	if game.DrawOffer != "" {
+		if !finishGame(actor, request.Side, resultDraw, "agreement") {
+			actor.conflict = true
+			return Game{}, errConflict
+		}
+		return *game, nil
+	}
+
+	game.DrawOffer = request.Side
+	if !saveGame(actor) {
+		actor.conflict = true
+		return Game{}, errConflict
+	}
+	broadcast(actor, eventDrawOffered, DrawOfferedPayload{
+		Side: request.Side,
+	})
+	return *game, nil
+}
+
+func chat(actor *gameActor, request ActionRequest) *APIError {
+	if request.Text == "" || len(request.Text) > maxChatLength {
+		return newAPIError(http.StatusBadRequest, "invalid_chat", "Chat messages must be between 1 and "+strconv.Itoa(maxChatLength)+" bytes.")
+	}
+	apiErr := verifyAction(actor, "chat", request)
+	if apiErr != nil {
+		return apiErr
+	}
+	broadcast(actor, eventChat, ChatPayload{
+		Side: request.Side,
+		Text: request.Text,
+	})
+	return nil
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var actors = map[uuid.UUID]*gameActor{}
+var actorsLock sync.Mutex
+
+// games being purged or archived, closed when done; guarded by actorsLock
+var retiring = map[uuid.UUID]chan struct{}{}
+
+// how many actors have stopped, so a load can tell if the game may have
+// changed while it was reading; guarded by actorsLock
+var actorsStopped uint64
+
+// moveHistory is what the rules need to know about earlier moves in a game.
+type moveHistory struct {
+	lastMove  BoardState
+	lastPly   int
+	movedFrom map[string]bool
+}
+
+// gameActor owns the current position of an active game and runs every
+// operation on it from a single goroutine.
+type gameActor struct {
+	game     Game
+	history  moveHistory
+	clock    gameClock
+	sequence int
+	// which players were last announced as connected
+	connected map[string]bool
+	// set when the game has changed elsewhere and must be reloaded
+	stale bool
+	// set by an operation that found the game changed elsewhere before it
+	// had changed anything itself, so it can be run again on a fresh copy
+	conflict bool
+	ops      chan func()
+	done     chan struct{}
+}
+
+func newMoveHistory(game Game) moveHistory {
+	history := moveHistory{
+		movedFrom: map[string]bool{},
+	}
+	for _, square := range lostCastlingSquares(game.StartFEN) {
+		history.movedFrom[square] = true
+	}
+	return history
+}
+
+func (history *moveHistory) record(move BoardState) {
+	if history.lastMove.State != nil {
+		history.lastPly++
+	}
+	history.lastMove = move
+	if move.StartPosition != "" {
+		history.movedFrom[move.StartPosition] = true
+	}
+}
+
+// loadActor returns the game's actor, starting one if needed. The game is
+// read from the db without holding actorsLock, so loading one game doesn't
+// hold up the rest.
+func loadActor(gameID uuid.UUID) (*gameActor, bool) {
+	for {
+		actorsLock.Lock()
+		actor, ok := actors[gameID]
+		released, retired := retiring[gameID]
+		stopped := actorsStopped
+		actorsLock.Unlock()
+		if ok {
+			return actor, true
+		}
+		if retired {
+			<-released
+			continue
+		}
+
+		actor, ok = readActor(gameID)
+		if !ok {
+			return nil, false
+		}
+
+		actorsLock.Lock()
+		if existing, ok := actors[gameID]; ok {
+			actorsLock.Unlock()
+			return existing, true
+		}
+		if _, retired := retiring[gameID]; retired || actorsStopped != stopped {
+			// an actor that stopped meanwhile may have changed the game
+			// after we read it
+			actorsLock.Unlock()
+			continue
+		}
+		actors[gameID] = actor
+		actorsLock.Unlock()
+		go actor.run()
+		return actor, true
+	}
+}
+
+func readActor(gameID uuid.UUID) (*gameActor, bool) {
+	game := Game{}
+	if db.First(&game, "game_id = ?", gameID).RecordNotFound() {
+		return nil, false
+	}
+
+	boardStates := []BoardState{}
+	db.Where("game_id = ?", gameID).Order("id").Find(&boardStates)
+
+	actor := &gameActor{
+		game:     game,
+		history:  newMoveHistory(game),
+		clock:    newGameClock(game),
+		sequence: lastSequence(gameID),
+		ops:      make(chan func()),
+		done:     make(chan struct{}),
+	}
+	for i, boardState := range boardStates {
+		actor.history.record(boardState)
+		if i > 0 {
+			actor.clock.record(boardState.MoveAuthor, boardState.CreatedAt)
+		}
+	}
+	presence, _ := broadcaster.Presence(gameID)
+	actor.connected = map[string]bool{
+		"WHITE": presence.WhiteConnected,
+		"BLACK": presence.BlackConnected,
+	}
+	return actor, true
+}
+
+func (actor *gameActor) run() {
+	idleTimeout := time.Duration(config.GameIdleTimeout) * time.Second
+	timer := time.NewTimer(idleTimeout)
+
+	// flag fires when the side to move runs out of time, so the game ends
+	// without waiting for anyone to move
+	flagTimer := time.NewTimer(0)
+	var flag <-chan time.Time
+	armFlag := func() {
+		if !flagTimer.Stop() {
+			select {
+			case <-flagTimer.C:
+			default:
+			}
+		}
+		flag = nil
+		left, ok := actor.checkFlag()
+		if ok {
+			flagTimer.Reset(left)
+			flag = flagTimer.C
+		}
+	}
+	armFlag()
+
+	for {
+		select {
+		case op := <-actor.ops:
+			// the opponent's actions and reads see the flag as soon as it falls
+			actor.checkFlag()
+			op()
+			if actor.stale {
+				actor.stop()
+				return
+			}
+			armFlag()
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(idleTimeout)
+		case <-flag:
+			armFlag()
+			if actor.stale {
+				// the game changed elsewhere before its flag could be recorded
+				actor.stop()
+				return
+			}
+		case <-timer.C:
+			if flag != nil {
+				// stay loaded while a clock is running so its flag can fall
+				timer.Reset(idleTimeout)
+				continue
+			}
+			actor.stop()
+			return
+		}
+	}
+}
+
+// checkFlag ends the game if the side to move has run out of time. Otherwise
+// it returns how long they have left, or false if no clock is running.
+func (actor *gameActor) checkFlag() (time.Duration, bool) {
+	if actor.game.Result != "" || !actor.clock.running() {
+		return 0, false
+	}
+	lastMove := actor.history.lastMove
+	side := nextMoveAuthor(lastMove)
+	left := actor.clock.timeLeft(side, time.Now())
+	if left <= 0 {
+		finishGame(actor, side, winner(lastMove.MoveAuthor), "timeout")
+		return 0, false
+	}
+	return left, true
+}
+
+func (actor *gameActor) stop() {
+	actorsLock.Lock()
+	defer actorsLock.Unlock()
+	delete(actors, actor.game.GameID)
+	actorsStopped++
+	close(actor.done)
+}
+
+// evictActor drops a game's actor, if it is loaded, so the next operation
+// on the game reloads it from the db. Until it has run, moves are checked
+// against what this instance last knew of the game.
+func evictActor(gameID uuid.UUID) {
+	actorsLock.Lock()
+	actor, ok := actors[gameID]
+	actorsLock.Unlock()
+	if ok {
+		actor.do(func() { actor.stale = true })
+	}
+}
+
+// do runs op on the actor goroutine and waits for it to finish. It returns
+// false if the actor was evicted before op could run. If op panics, the
+// actor is dropped, since op may have left it half changed, and the panic is
+// returned as an internal error.
+func (actor *gameActor) do(op func()) (bool, *APIError) {
+	finished := make(chan struct{})
+	var failure *APIError
+	select {
+	case actor.ops <- func() {
+		defer close(finished)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				fmt.Println("Recovered from panic in game "+actor.game.GameID.String()+":", recovered)
+				fmt.Println(string(debug.Stack()))
+				actor.stale = true
+				failure = errInternal
+			}
+		}()
+		op()
+	}:
+		<-finished
+		return true, failure
+	case <-actor.done:
+		return false, nil
+	}
+}
+
+// withGame runs op against the active game, loading it from the db if needed.
+// An op that loses a race with another instance is run again, up to
+// maxConflicts times. It returns errGameNotFound if the game does not exist,
+// or errInternal if op panicked.
+func withGame(gameID uuid.UUID, op func(actor *gameActor)) *APIError {
+	conflicts := 0
+	for {
+		actor, ok := loadActor(gameID)
+		if !ok {
+			return errGameNotFound
+		}
+		conflict := false
+		ran, failure := actor.do(func() {
+			op(actor)
+			conflict = actor.conflict
+		})
+		if !ran {
+			continue
+		}
+		if failure != nil || !conflict || conflicts == maxConflicts {
+			return failure
+		}
+		conflicts++
+	}
+}
+
+// how many times a change that raced another instance is tried again
+var maxConflicts = 3
+
+var errConflict = newAPIError(http.StatusConflict, "conflict", "The game changed on another server at the same time; try again.")
+
+// saveGame writes the actor's changes to its game, unless another instance
+// changed the game since the actor read it. Then the actor is marked stale
+// and nothing is written.
+func saveGame(actor *gameActor) bool {
+	game := &actor.game
+	saved := db.Model(&Game{}).Where("id = ? AND revision = ?", game.ID, game.Revision).Updates(map[string]interface{}{
+		"white_player":  game.WhitePlayer,
+		"black_player":  game.BlackPlayer,
+		"result":        game.Result,
+		"result_reason": game.ResultReason,
+		"draw_offer":    game.DrawOffer,
+		"revision":      game.Revision + 1,
+	}).RowsAffected == 1
+	if !saved {
+		actor.stale = true
+		return false
+	}
+	game.Revision++
+	return true
+}
+
+// storeMove stores a move along with any change to the game, unless another
+// instance changed the game or stored a move for the same ply first.
+func storeMove(actor *gameActor, boardState *BoardState) bool {
+	game := &actor.game
+	tx := db.Begin()
+	saved := tx.Model(&Game{}).Where("id = ? AND revision = ?", game.ID, game.Revision).Updates(map[string]interface{}{
+		"draw_offer": game.DrawOffer,
+		"revision":   game.Revision + 1,
+	}).RowsAffected == 1
+	if !saved || tx.Create(boardState).Error != nil || tx.Commit().Error != nil {
+		tx.Rollback()
+		actor.stale = true
+		return false
+	}
+	game.Revision++
+	return true
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// flaggingActor runs an actor for a stored timed game in which white, to
+// move, has 50ms left.
+func flaggingActor(t *testing.T) (*gameActor, Game) {
+	t.Helper()
+	gameID, history := testPosition(t, startFEN)
+	game := storeGame(t, Game{GameID: gameID, InitialSeconds: 60})
+	actor := &gameActor{
+		game:    game,
+		history: history,
+		clock:   newGameClock(game),
+		ops:     make(chan func()),
+		done:    make(chan struct{}),
+	}
+	actor.clock.moves = 2
+	actor.clock.lastMoveAt = time.Now().Add(-time.Minute + 50*time.Millisecond)
+	go actor.run()
+	return actor, game
+}
+
+func TestFlagFallsWithoutAMove(t *testing.T) {
+	actor, game := flaggingActor(t)
+	defer actor.do(func() { actor.stale = true })
+
+	// nothing touches the actor, so only its timer can have ended the game
+	time.Sleep(200 * time.Millisecond)
+	stored := Game{}
+	db.First(&stored, "game_id = ?", game.GameID)
+	if stored.Result != resultBlackWon || stored.ResultReason != "timeout" {
+		t.Errorf("got %q by %q, want %q by timeout", stored.Result, stored.ResultReason, resultBlackWon)
+	}
+}
+
+func TestFlagStopsStaleActor(t *testing.T) {
+	actor, game := flaggingActor(t)
+	defer actor.do(func() { actor.stale = true })
+	// another instance changes the game before the flag falls
+	db.Model(&Game{}).Where("id = ?", game.ID).UpdateColumn("revision", game.Revision+1)
+
+	select {
+	case <-actor.done:
+	case <-time.After(time.Second):
+		t.Fatal("actor kept running on a stale game")
+	}
+	stored := Game{}
+	db.First(&stored, "game_id = ?", game.GameID)
+	if stored.Result != "" {
+		t.Errorf("stale actor recorded %q", stored.Result)
+	}
+}
+
+// testInstances loads the same stored game twice, the way two instances
+// sharing a database would.
+func testInstances(t *testing.T) (*gameActor, *gameActor, ed25519.PrivateKey) {
+	t.Helper()
+	whiteKey, whitePriv, _ := ed25519.GenerateKey(nil)
+	blackKey, _, _ := ed25519.GenerateKey(nil)
+	game := storeGame(t, Game{GameID: uuid.NewV4(), WhitePlayer: whiteKey, BlackPlayer: blackKey, Variant: variantStandard})
+	start, _ := startingPosition("")
+	firstState := startingBoardState(game.GameID, start)
+	db.Create(&firstState)
+	first, _ := readActor(game.GameID)
+	second, _ := readActor(game.GameID)
+	return first, second, whitePriv
+}
+
+func TestRacingInstances(t *testing.T) {
+	first, second, whitePriv := testInstances(t)
+	gameID := first.game.GameID
+	move := func(actor *gameActor, from string, to string) *APIError {
+		board := submit(deserializeBoard(actor.history.lastMove.State), from, to, 0)
+		_, apiErr := makeMove(actor, ReceivedBoardState{
+			GameID: gameID,
+			State:  board,
+			Signed: hex.EncodeToString(ed25519.Sign(whitePriv, serializeBoard(board))),
+		})
+		return apiErr
+	}
+
+	if apiErr := move(first, "E2", "E4"); apiErr != nil {
+		t.Fatal(apiErr.Message)
+	}
+	if apiErr := move(second, "D2", "D4"); apiErr != errConflict {
+		t.Fatalf("second move for the same ply got %v, want a conflict", apiErr)
+	}
+	if !second.conflict || !second.stale {
+		t.Error("losing instance was not marked to reload and retry")
+	}
+	count := 0
+	db.Model(&BoardState{}).Where("game_id = ?", gameID).Count(&count)
+	if count != 2 {
+		t.Errorf("got %d board states, want 2", count)
+	}
+
+	// the losing instance's event is numbered after the winner's
+	sequence := first.sequence
+	broadcast(second, eventChat, ChatPayload{Side: "BLACK", Text: "hi"})
+	if second.sequence != sequence+1 {
+		t.Errorf("got sequence %d, want %d", second.sequence, sequence+1)
+	}
+	var events, sequences int
+	row := db.Model(&StoredEvent{}).Where("game_id = ?", gameID).Select("COUNT(*), COUNT(DISTINCT sequence)").Row()
+	if err := row.Scan(&events, &sequences); err != nil || events != sequences {
+		t.Errorf("%d events share %d sequences", events, sequences)
+	}
+
+	// a game finished on one instance can't be finished again on the other
+	if !finishGame(first, "WHITE", resultWhiteWon, "resignation") {
+		t.Fatal("first finish failed")
+	}
+	third, _ := readActor(gameID)
+	third.game.Revision--
+	if finishGame(third, "BLACK", resultBlackWon, "resignation") {
+		t.Error("stale instance finished the game again")
+	}
+	if third.game.Result != resultWhiteWon {
+		t.Errorf("stale instance kept result %q", third.game.Result)
+	}
+}
+
+func TestWithGameRetriesConflicts(t *testing.T) {
+	_, other, _ := testInstances(t)
+	gameID := other.game.GameID
+	// load this instance's actor, then change the game behind its back
+	withGame(gameID, func(*gameActor) {})
+	other.game.DrawOffer = "WHITE"
+	if !saveGame(other) {
+		t.Fatal("other instance could not save")
+	}
+
+	attempts := 0
+	var drawOffer string
+	withGame(gameID, func(actor *gameActor) {
+		attempts++
+		drawOffer = actor.game.DrawOffer
+		if !saveGame(actor) {
+			actor.conflict = true
+		}
+	})
+	if attempts != 2 || drawOffer != "WHITE" {
+		t.Errorf("got %d attempts seeing draw offer %q, want 2 seeing WHITE", attempts, drawOffer)
+	}
+}
+
+func TestPanickingOpIsRecovered(t *testing.T) {
+	game := storeGame(t, Game{GameID: uuid.NewV4(), Variant: variantStandard})
+	failure := withGame(game.GameID, func(*gameActor) {
+		panic("boom")
+	})
+	if failure != errInternal {
+		t.Fatalf("got %v, want an internal error", failure)
+	}
+	if failure = withGame(game.GameID, func(*gameActor) {}); failure != nil {
+		t.Errorf("game did not reload after a panic: %v", failure)
+	}
+}
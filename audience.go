@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// who an event is being sent to, if not the WHITE or BLACK player
+var audienceSpectator = "SPECTATOR"
+
+// SocketChallenge is sent when a websocket connects. A player proves which
+// side they are by signing "auth:<gameID>:<challenge>" and sending it in an
+// auth request. Until then the socket is a spectator.
+type SocketChallenge struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+}
+
+// AuthResult acknowledges an auth request with the audience the socket now
+// belongs to.
+type AuthResult struct {
+	Audience string `json:"audience"`
+}
+
+// eventView decides what an audience sees of an event. It returns false if
+// the audience shouldn't be sent the event at all, in which case they see
+// a gap in the sequence.
+type eventView func(game Game, audience string, event EventEnvelope) (EventEnvelope, bool)
+
+// eventViews holds the view for each variant that hides something from
+// some audience. Variants without one show every event to everyone.
+var eventViews = map[string]eventView{}
+
+func viewEvent(game Game, audience string, event EventEnvelope) (EventEnvelope, bool) {
+	view, ok := eventViews[game.Variant]
+	if !ok {
+		return event, true
+	}
+	return view(game, audience, event)
+}
+
+// viewEvents filters a replay for one audience.
+func viewEvents(game Game, audience string, events []EventEnvelope) []EventEnvelope {
+	visible := []EventEnvelope{}
+	for _, event := range events {
+		event, ok := viewEvent(game, audience, event)
+		if ok {
+			visible = append(visible, event)
+		}
+	}
+	return visible
+}
+
+func newChallenge() string {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	check(err)
+	return hex.EncodeToString(nonce)
+}
+
+// authenticate makes a socket the audience of the side whose player signed
+// its challenge.
+func authenticate(actor *gameActor, sub *SocketSub, request ActionRequest) (AuthResult, *APIError) {
+	request.Text = sub.challenge
+	apiErr := verifyAction(actor, "auth", request)
+	if apiErr != nil {
+		return AuthResult{}, apiErr
+	}
+	sub.setAudience(request.Side)
+	updatePresence(actor)
+	return AuthResult{Audience: request.Side}, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var variantTestView = "testView"
+
+// testView blanks chat for spectators and hides draw offers from them.
+func testView(game Game, audience string, event EventEnvelope) (EventEnvelope, bool) {
+	if audience != audienceSpectator {
+		return event, true
+	}
+	switch event.Type {
+	case eventChat:
+		chat := event.Payload.(ChatPayload)
+		chat.Text = ""
+		event.Payload = chat
+	case eventDrawOffered:
+		return event, false
+	}
+	return event, true
+}
+
+func useTestView(t *testing.T) {
+	eventViews[variantTestView] = testView
+	t.Cleanup(func() { delete(eventViews, variantTestView) })
+}
+
+// testSub subscribes a socket without a connection, so the test can read
+// what it would have been sent.
+func testSub(t *testing.T, gameID uuid.UUID, audience string) *SocketSub {
+	sub := &SocketSub{GameID: gameID, send: make(chan interface{}, 8), audience: audience}
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	if socketSubs[gameID] == nil {
+		socketSubs[gameID] = map[*SocketSub]bool{}
+	}
+	socketSubs[gameID][sub] = true
+	t.Cleanup(func() {
+		socketSubsLock.Lock()
+		defer socketSubsLock.Unlock()
+		delete(socketSubs[gameID], sub)
+		if len(socketSubs[gameID]) == 0 {
+			delete(socketSubs, gameID)
+		}
+	})
+	return sub
+}
+
+// sent is what a socket has queued, as "type:text" for chat and the type
+// for anything else.
+func sent(sub *SocketSub) []string {
+	got := []string{}
+	for {
+		select {
+		case message := <-sub.send:
+			event := message.(EventEnvelope)
+			if chat, ok := event.Payload.(ChatPayload); ok {
+				got = append(got, event.Type+":"+chat.Text)
+				continue
+			}
+			got = append(got, event.Type)
+		default:
+			return got
+		}
+	}
+}
+
+func chatAndDraw(gameID uuid.UUID) []EventEnvelope {
+	return []EventEnvelope{
+		{Type: eventChat, GameID: gameID, Sequence: 1, Payload: ChatPayload{Side: "WHITE", Text: "hi"}},
+		{Type: eventDrawOffered, GameID: gameID, Sequence: 2, Payload: DrawOfferedPayload{Side: "WHITE"}},
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestViewEvents(t *testing.T) {
+	useTestView(t)
+	tests := []struct {
+		variant  string
+		audience string
+		want     []string
+	}{
+		{variantStandard, audienceSpectator, []string{"chat:hi", "draw_offered"}},
+		{variantTestView, "WHITE", []string{"chat:hi", "draw_offered"}},
+		{variantTestView, "BLACK", []string{"chat:hi", "draw_offered"}},
+		{variantTestView, audienceSpectator, []string{"chat:"}},
+	}
+
+	for _, test := range tests {
+		gameID := uuid.NewV4()
+		sub := testSub(t, gameID, test.audience)
+		for _, event := range viewEvents(Game{GameID: gameID, Variant: test.variant}, test.audience, chatAndDraw(gameID)) {
+			sub.send <- event
+		}
+		if got := sent(sub); !equalStrings(got, test.want) {
+			t.Errorf("%s %s: got %v, want %v", test.variant, test.audience, got, test.want)
+		}
+	}
+}
+
+func TestPublishSocketAppliesViews(t *testing.T) {
+	useTestView(t)
+	gameID := uuid.NewV4()
+	game := Game{GameID: gameID, Variant: variantTestView}
+	white := testSub(t, gameID, "WHITE")
+	spectator := testSub(t, gameID, audienceSpectator)
+	for _, event := range chatAndDraw(gameID) {
+		publishSocket(game, event)
+	}
+
+	if got, want := sent(white), []string{"chat:hi", "draw_offered"}; !equalStrings(got, want) {
+		t.Errorf("white got %v, want %v", got, want)
+	}
+	if got, want := sent(spectator), []string{"chat:"}; !equalStrings(got, want) {
+		t.Errorf("spectator got %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeEventsAppliesViews(t *testing.T) {
+	useTestView(t)
+	gameID, history := testPosition(t, startFEN)
+	actor := &gameActor{game: Game{GameID: gameID, Variant: variantTestView}, history: history}
+	broadcast(actor, eventChat, ChatPayload{Side: "WHITE", Text: "hi"})
+	broadcast(actor, eventDrawOffered, DrawOfferedPayload{Side: "WHITE"})
+
+	tests := []struct {
+		audience string
+		want     []string
+	}{
+		{"WHITE", []string{"chat:hi", "draw_offered"}},
+		{audienceSpectator, []string{"chat:"}},
+	}
+	for _, test := range tests {
+		sub := testSub(t, gameID, test.audience)
+		_, apiErr := subscribeEvents(actor, sub, 0)
+		if apiErr != nil {
+			t.Fatal(apiErr.Message)
+		}
+		if got := sent(sub); !equalStrings(got, test.want) {
+			t.Errorf("%s replay got %v, want %v", test.audience, got, test.want)
+		}
+	}
+}
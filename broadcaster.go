@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// BroadcastConfig picks how events reach clients connected to other server
+// instances.
+type BroadcastConfig struct {
+	// "local" when there is a single instance, or "database" to pick up
+	// events other instances store in the shared database
+	Backplane string `json:"backplane"`
+	// how often the database backplane looks for new events
+	PollMillis int `json:"pollMillis"`
+	// how far back each poll looks again, for events that became visible
+	// late; must cover the difference between instances' clocks
+	LookbackMillis int `json:"lookbackMillis"`
+}
+
+var backplaneLocal = "local"
+var backplaneDatabase = "database"
+
+// Broadcaster fans a game's events out to every socket and watcher of the
+// game, whichever instance they are connected to, and tracks who is
+// connected across instances.
+type Broadcaster interface {
+	// Publish sends an event that has just been stored.
+	Publish(game Game, event EventEnvelope)
+	// SharePresence records who this instance has connected to a game.
+	SharePresence(gameID uuid.UUID, local Presence)
+	// Presence is who is connected to a game on every instance, and when
+	// that last changed.
+	Presence(gameID uuid.UUID) (Presence, time.Time)
+	// Run delivers events published by other instances, until the process
+	// exits.
+	Run()
+}
+
+// this instance, so it can tell its own events from everyone else's
+var nodeID = uuid.NewV4().String()
+
+var broadcaster Broadcaster
+
+func newBroadcaster(broadcastConfig BroadcastConfig) Broadcaster {
+	if broadcastConfig.Backplane == backplaneDatabase {
+		return &databaseBroadcaster{
+			interval:  time.Duration(broadcastConfig.PollMillis) * time.Millisecond,
+			lookback:  time.Duration(broadcastConfig.LookbackMillis) * time.Millisecond,
+			delivered: map[uuid.UUID]int{},
+			storedAt:  map[uuid.UUID]time.Time{},
+		}
+	}
+	return localBroadcaster{}
+}
+
+// deliverEvent sends an event to the sockets and watchers connected to
+// this instance.
+func deliverEvent(game Game, event EventEnvelope) {
+	publishSocket(game, event)
+	// sse and grpc watchers don't authenticate, so they see what spectators do
+	view, ok := viewEvent(game, audienceSpectator, event)
+	if ok {
+		notifyWatchers(event.GameID, view)
+	}
+}
+
+// localBroadcaster only reaches clients of this instance.
+type localBroadcaster struct{}
+
+func (localBroadcaster) Publish(game Game, event EventEnvelope) {
+	deliverEvent(game, event)
+}
+
+func (localBroadcaster) SharePresence(gameID uuid.UUID, local Presence) {}
+
+func (localBroadcaster) Presence(gameID uuid.UUID) (Presence, time.Time) {
+	return gamePresence(gameID)
+}
+
+func (localBroadcaster) Run() {}
+
+// databaseBroadcaster finds events other instances have stored by polling
+// the events table. A database like MySQL can make a row visible after rows
+// stored later, so each poll also reads again the events stored within the
+// lookback, and skips those already seen by their sequence, which is unique
+// within a game and committed in order.
+type databaseBroadcaster struct {
+	interval time.Duration
+	lookback time.Duration
+	// when the last poll started
+	since time.Time
+	// the last sequence seen for each game with recent events, and when it
+	// was stored
+	delivered map[uuid.UUID]int
+	storedAt  map[uuid.UUID]time.Time
+	// when this instance last marked its presence rows as alive
+	heartbeat time.Time
+}
+
+// how often an instance marks its presence rows as alive, and how long
+// other instances trust them without that
+var presenceHeartbeat = 10 * time.Second
+var presenceExpiry = 3 * presenceHeartbeat
+
+func (broadcaster *databaseBroadcaster) Publish(game Game, event EventEnvelope) {
+	deliverEvent(game, event)
+}
+
+func (broadcaster *databaseBroadcaster) SharePresence(gameID uuid.UUID, local Presence) {
+	now := time.Now()
+	counts := map[string]interface{}{
+		"white_connected": local.WhiteConnected,
+		"black_connected": local.BlackConnected,
+		"watching":        local.Watching,
+		"changed_at":      now,
+	}
+	if db.Model(&NodePresence{}).Where("game_id = ? AND node = ?", gameID, nodeID).Updates(counts).RowsAffected == 0 {
+		db.Create(&NodePresence{
+			GameID:         gameID,
+			Node:           nodeID,
+			WhiteConnected: local.WhiteConnected,
+			BlackConnected: local.BlackConnected,
+			Watching:       local.Watching,
+			ChangedAt:      now,
+		})
+	}
+}
+
+func (broadcaster *databaseBroadcaster) Presence(gameID uuid.UUID) (Presence, time.Time) {
+	presence, changed := gamePresence(gameID)
+	others := []NodePresence{}
+	db.Where("game_id = ? AND node <> ? AND updated_at > ?", gameID, nodeID, time.Now().Add(-presenceExpiry)).Find(&others)
+	for _, other := range others {
+		presence.WhiteConnected = presence.WhiteConnected || other.WhiteConnected
+		presence.BlackConnected = presence.BlackConnected || other.BlackConnected
+		presence.Watching += other.Watching
+		if other.ChangedAt.After(changed) {
+			changed = other.ChangedAt
+		}
+	}
+	return presence, changed
+}
+
+func (broadcaster *databaseBroadcaster) Run() {
+	broadcaster.since = time.Now()
+	broadcaster.poll(false)
+	fmt.Println("Polling for events from other instances as node " + nodeID)
+
+	ticker := time.NewTicker(broadcaster.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		broadcaster.poll(true)
+		if time.Since(broadcaster.heartbeat) >= presenceHeartbeat {
+			broadcaster.beat()
+		}
+	}
+}
+
+// poll delivers the events other instances have stored since the last
+// poll. The first poll only notes what has already happened.
+func (broadcaster *databaseBroadcaster) poll(deliver bool) {
+	now := time.Now()
+	stored := []StoredEvent{}
+	db.Where("created_at > ?", broadcaster.since.Add(-broadcaster.lookback)).Order("game_id, sequence").Find(&stored)
+	broadcaster.since = now
+	for _, row := range stored {
+		if row.Sequence <= broadcaster.delivered[row.GameID] {
+			continue
+		}
+		broadcaster.delivered[row.GameID] = row.Sequence
+		broadcaster.storedAt[row.GameID] = row.CreatedAt
+		if !deliver || row.Node == nodeID {
+			continue
+		}
+		event, ok := decodeEvent(row)
+		if !ok {
+			fmt.Println("Skipping undecodable event " + row.Type + " for " + row.GameID.String())
+			continue
+		}
+		// the game changed on another instance, so what we know of it is stale
+		evictActor(row.GameID)
+		game := Game{}
+		if db.First(&game, "game_id = ?", row.GameID).RecordNotFound() {
+			continue
+		}
+		deliverEvent(game, event)
+	}
+
+	// events stored before the next poll's lookback won't be read again
+	for gameID, storedAt := range broadcaster.storedAt {
+		if storedAt.Before(now.Add(-2 * broadcaster.lookback)) {
+			delete(broadcaster.storedAt, gameID)
+			delete(broadcaster.delivered, gameID)
+		}
+	}
+}
+
+// beat marks this instance's presence rows as alive and drops those of
+// instances that have stopped.
+func (broadcaster *databaseBroadcaster) beat() {
+	now := time.Now()
+	broadcaster.heartbeat = now
+	db.Model(&NodePresence{}).Where("node = ? AND (white_connected = ? OR black_connected = ? OR watching > 0)", nodeID, true, true).UpdateColumn("updated_at", now)
+	db.Unscoped().Where("updated_at < ?", now.Add(-presenceExpiry)).Delete(NodePresence{})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// otherNodeEvent stores a chat event as if another instance had, with the
+// given row id.
+func otherNodeEvent(t *testing.T, id uint, gameID uuid.UUID) {
+	t.Helper()
+	event := EventEnvelope{
+		Version:   eventEnvelopeVersion,
+		Type:      eventChat,
+		GameID:    gameID,
+		Sequence:  lastSequence(gameID) + 1,
+		Timestamp: time.Now(),
+		Payload:   ChatPayload{Side: "WHITE", Text: "hi"},
+	}
+	payload, _ := json.Marshal(event)
+	stored := StoredEvent{GameID: gameID, Node: "other", Sequence: event.Sequence, Type: event.Type, Payload: payload}
+	stored.ID = id
+	if err := db.Create(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// On MySQL a row can become visible after rows with higher ids, so polling
+// must not skip events stored out of id order.
+func TestPollDeliversEventsOutOfIDOrder(t *testing.T) {
+	early := storeGame(t, Game{GameID: uuid.NewV4(), Variant: variantStandard})
+	late := storeGame(t, Game{GameID: uuid.NewV4(), Variant: variantStandard})
+	var maxID uint
+	check(db.Model(&StoredEvent{}).Select("COALESCE(MAX(id), 0)").Row().Scan(&maxID))
+
+	polling := newBroadcaster(BroadcastConfig{Backplane: "database", PollMillis: 50, LookbackMillis: 5000}).(*databaseBroadcaster)
+	polling.since = time.Now()
+	polling.poll(false)
+
+	watcher := watchGame(early.GameID)
+	defer unwatchGame(early.GameID, watcher)
+
+	otherNodeEvent(t, maxID+100, late.GameID)
+	polling.poll(true)
+	otherNodeEvent(t, maxID+50, early.GameID)
+	polling.poll(true)
+	polling.poll(true)
+
+	delivered := 0
+	for {
+		select {
+		case message := <-watcher:
+			if event, ok := message.(EventEnvelope); !ok || event.Type != eventChat {
+				t.Errorf("got %v, want the chat event", message)
+			}
+			delivered++
+			continue
+		default:
+		}
+		break
+	}
+	if delivered != 1 {
+		t.Errorf("event was delivered %d times, want once", delivered)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var cacheControlImmutable = "public, max-age=31536000, immutable"
+var cacheControlRevalidate = "no-cache"
+
+// gameETag names one representation of a game at a given version, usually
+// its last ply, so the same game fetched with different query parameters or
+// formats gets its own tag.
+func gameETag(req *http.Request, version string) string {
+	variant := crc32.ChecksumIEEE([]byte(req.URL.RawQuery + "|" + responseType(req)))
+	return fmt.Sprintf(`"%s-%08x"`, version, variant)
+}
+
+// notModified sets the caching headers for a response and reports whether
+// the client's cached copy is still current, in which case a 304 has been
+// written and the handler should stop.
+func notModified(res http.ResponseWriter, req *http.Request, etag string, lastModified time.Time, immutable bool) bool {
+	res.Header().Set("ETag", etag)
+	res.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	res.Header().Set("Vary", "Accept")
+	if immutable {
+		res.Header().Set("Cache-Control", cacheControlImmutable)
+	} else {
+		res.Header().Set("Cache-Control", cacheControlRevalidate)
+	}
+
+	fresh := false
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				fresh = true
+			}
+		}
+	} else if since, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil {
+		fresh = !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if fresh {
+		res.WriteHeader(http.StatusNotModified)
+	}
+	return fresh
+}
@@ -0,0 +1,313 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package chesspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ChessClient is the client API for Chess service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ChessClient interface {
+	// CreateNonce issues the nonce a creator signs to take a seat.
+	CreateNonce(ctx context.Context, in *CreateNonceRequest, opts ...grpc.CallOption) (*CreateNonceResponse, error)
+	CreateGame(ctx context.Context, in *CreateGameRequest, opts ...grpc.CallOption) (*Game, error)
+	JoinGame(ctx context.Context, in *JoinGameRequest, opts ...grpc.CallOption) (*Game, error)
+	MakeMove(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*BoardState, error)
+	GetGame(ctx context.Context, in *GetGameRequest, opts ...grpc.CallOption) (*GameHistory, error)
+	// WatchGame streams joins and moves for a game until the client hangs up.
+	WatchGame(ctx context.Context, in *WatchGameRequest, opts ...grpc.CallOption) (Chess_WatchGameClient, error)
+}
+
+type chessClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChessClient(cc grpc.ClientConnInterface) ChessClient {
+	return &chessClient{cc}
+}
+
+func (c *chessClient) CreateNonce(ctx context.Context, in *CreateNonceRequest, opts ...grpc.CallOption) (*CreateNonceResponse, error) {
+	out := new(CreateNonceResponse)
+	err := c.cc.Invoke(ctx, "/chess.Chess/CreateNonce", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chessClient) CreateGame(ctx context.Context, in *CreateGameRequest, opts ...grpc.CallOption) (*Game, error) {
+	out := new(Game)
+	err := c.cc.Invoke(ctx, "/chess.Chess/CreateGame", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chessClient) JoinGame(ctx context.Context, in *JoinGameRequest, opts ...grpc.CallOption) (*Game, error) {
+	out := new(Game)
+	err := c.cc.Invoke(ctx, "/chess.Chess/JoinGame", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chessClient) MakeMove(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*BoardState, error) {
+	out := new(BoardState)
+	err := c.cc.Invoke(ctx, "/chess.Chess/MakeMove", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chessClient) GetGame(ctx context.Context, in *GetGameRequest, opts ...grpc.CallOption) (*GameHistory, error) {
+	out := new(GameHistory)
+	err := c.cc.Invoke(ctx, "/chess.Chess/GetGame", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chessClient) WatchGame(ctx context.Context, in *WatchGameRequest, opts ...grpc.CallOption) (Chess_WatchGameClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Chess_ServiceDesc.Streams[0], "/chess.Chess/WatchGame", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chessWatchGameClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Chess_WatchGameClient interface {
+	Recv() (*GameEvent, error)
+	grpc.ClientStream
+}
+
+type chessWatchGameClient struct {
+	grpc.ClientStream
+}
+
+func (x *chessWatchGameClient) Recv() (*GameEvent, error) {
+	m := new(GameEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ChessServer is the server API for Chess service.
+// All implementations must embed UnimplementedChessServer
+// for forward compatibility
+type ChessServer interface {
+	// CreateNonce issues the nonce a creator signs to take a seat.
+	CreateNonce(context.Context, *CreateNonceRequest) (*CreateNonceResponse, error)
+	CreateGame(context.Context, *CreateGameRequest) (*Game, error)
+	JoinGame(context.Context, *JoinGameRequest) (*Game, error)
+	MakeMove(context.Context, *MoveRequest) (*BoardState, error)
+	GetGame(context.Context, *GetGameRequest) (*GameHistory, error)
+	// WatchGame streams joins and moves for a game until the client hangs up.
+	WatchGame(*WatchGameRequest, Chess_WatchGameServer) error
+	mustEmbedUnimplementedChessServer()
+}
+
+// UnimplementedChessServer must be embedded to have forward compatible implementations.
+type UnimplementedChessServer struct {
+}
+
+func (UnimplementedChessServer) CreateNonce(context.Context, *CreateNonceRequest) (*CreateNonceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateNonce not implemented")
+}
+func (UnimplementedChessServer) CreateGame(context.Context, *CreateGameRequest) (*Game, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateGame not implemented")
+}
+func (UnimplementedChessServer) JoinGame(context.Context, *JoinGameRequest) (*Game, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinGame not implemented")
+}
+func (UnimplementedChessServer) MakeMove(context.Context, *MoveRequest) (*BoardState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MakeMove not implemented")
+}
+func (UnimplementedChessServer) GetGame(context.Context, *GetGameRequest) (*GameHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGame not implemented")
+}
+func (UnimplementedChessServer) WatchGame(*WatchGameRequest, Chess_WatchGameServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchGame not implemented")
+}
+func (UnimplementedChessServer) mustEmbedUnimplementedChessServer() {}
+
+// UnsafeChessServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChessServer will
+// result in compilation errors.
+type UnsafeChessServer interface {
+	mustEmbedUnimplementedChessServer()
+}
+
+func RegisterChessServer(s grpc.ServiceRegistrar, srv ChessServer) {
+	s.RegisterService(&Chess_ServiceDesc, srv)
+}
+
+func _Chess_CreateNonce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNonceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChessServer).CreateNonce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chess.Chess/CreateNonce",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChessServer).CreateNonce(ctx, req.(*CreateNonceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chess_CreateGame_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateGameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChessServer).CreateGame(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chess.Chess/CreateGame",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChessServer).CreateGame(ctx, req.(*CreateGameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chess_JoinGame_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinGameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChessServer).JoinGame(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chess.Chess/JoinGame",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChessServer).JoinGame(ctx, req.(*JoinGameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chess_MakeMove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChessServer).MakeMove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chess.Chess/MakeMove",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChessServer).MakeMove(ctx, req.(*MoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chess_GetGame_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChessServer).GetGame(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chess.Chess/GetGame",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChessServer).GetGame(ctx, req.(*GetGameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chess_WatchGame_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchGameRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChessServer).WatchGame(m, &chessWatchGameServer{stream})
+}
+
+type Chess_WatchGameServer interface {
+	Send(*GameEvent) error
+	grpc.ServerStream
+}
+
+type chessWatchGameServer struct {
+	grpc.ServerStream
+}
+
+func (x *chessWatchGameServer) Send(m *GameEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Chess_ServiceDesc is the grpc.ServiceDesc for Chess service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Chess_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "chess.Chess",
+	HandlerType: (*ChessServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateNonce",
+			Handler:    _Chess_CreateNonce_Handler,
+		},
+		{
+			MethodName: "CreateGame",
+			Handler:    _Chess_CreateGame_Handler,
+		},
+		{
+			MethodName: "JoinGame",
+			Handler:    _Chess_JoinGame_Handler,
+		},
+		{
+			MethodName: "MakeMove",
+			Handler:    _Chess_MakeMove_Handler,
+		},
+		{
+			MethodName: "GetGame",
+			Handler:    _Chess_GetGame_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchGame",
+			Handler:       _Chess_WatchGame_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "chess.proto",
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"time"
+)
+
+// gameClock tracks the time left for each side of a timed game. Each
+// side's clock starts after their first move, and the increment is added
+// after every move from then on.
+type gameClock struct {
+	timed      bool
+	increment  time.Duration
+	remaining  map[string]time.Duration
+	lastMoveAt time.Time
+	moves      int
+}
+
+func newGameClock(game Game) gameClock {
+	initial := time.Duration(game.InitialSeconds) * time.Second
+	return gameClock{
+		timed:     game.InitialSeconds > 0,
+		increment: time.Duration(game.IncrementSeconds) * time.Second,
+		remaining: map[string]time.Duration{
+			"WHITE": initial,
+			"BLACK": initial,
+		},
+	}
+}
+
+// running reports whether the side to move is using up their time.
+func (clock *gameClock) running() bool {
+	return clock.timed && clock.moves >= 2
+}
+
+// record charges a move to the side that made it.
+func (clock *gameClock) record(side string, at time.Time) {
+	if !clock.timed {
+		return
+	}
+	if clock.running() {
+		clock.remaining[side] += clock.increment - at.Sub(clock.lastMoveAt)
+	}
+	clock.lastMoveAt = at
+	clock.moves++
+}
+
+// timeLeft is how much time a side has left if they are the side to move.
+func (clock *gameClock) timeLeft(side string, now time.Time) time.Duration {
+	return clock.remaining[side] - now.Sub(clock.lastMoveAt)
+}
+
+func (clock *gameClock) payload(sideToMove string) ClockUpdatePayload {
+	running := ""
+	if clock.running() {
+		running = sideToMove
+	}
+	return ClockUpdatePayload{
+		WhiteMillis: clock.remaining["WHITE"].Milliseconds(),
+		BlackMillis: clock.remaining["BLACK"].Milliseconds(),
+		Running:     running,
+		Since:       clock.lastMoveAt,
+	}
+}
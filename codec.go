@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	uuid "github.com/satori/go.uuid"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var contentTypeJSON = "application/json"
+var contentTypeMsgpack = "application/x-msgpack"
+
+// websocket subprotocols, in order of preference when a client offers both
+var socketProtocolJSON = "chess.json"
+var socketProtocolMsgpack = "chess.msgpack"
+
+func init() {
+	// send game IDs as strings, the same as in json
+	msgpack.Register(uuid.UUID{},
+		func(enc *msgpack.Encoder, value reflect.Value) error {
+			return enc.EncodeString(value.Interface().(uuid.UUID).String())
+		},
+		func(dec *msgpack.Decoder, value reflect.Value) error {
+			str, err := dec.DecodeString()
+			if err != nil {
+				return err
+			}
+			id, err := uuid.FromString(str)
+			if err != nil {
+				return err
+			}
+			value.Set(reflect.ValueOf(id))
+			return nil
+		})
+}
+
+func marshalMsgpack(body interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	err := enc.Encode(body)
+	return buf.Bytes(), err
+}
+
+func unmarshalMsgpack(data []byte, body interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(body)
+}
+
+// responseType picks the content type to answer with from the Accept header.
+func responseType(req *http.Request) string {
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if mediaType == contentTypeMsgpack {
+			return contentTypeMsgpack
+		}
+		if mediaType == contentTypeJSON {
+			return contentTypeJSON
+		}
+	}
+	return contentTypeJSON
+}
+
+func writeBody(res http.ResponseWriter, req *http.Request, status int, body interface{}) {
+	contentType := responseType(req)
+	var byteRes []byte
+	var err error
+	if contentType == contentTypeMsgpack {
+		byteRes, err = marshalMsgpack(body)
+	} else {
+		byteRes, err = json.Marshal(body)
+	}
+	check(err)
+
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Set("Vary", "Accept")
+	res.WriteHeader(status)
+	res.Write(byteRes)
+}
+
+func writeJSON(res http.ResponseWriter, req *http.Request, body interface{}) {
+	writeBody(res, req, http.StatusOK, body)
+}
+
+// readBody decodes the request body according to its Content-Type.
+func readBody(req *http.Request, body interface{}) *APIError {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, "unreadable_body", "Could not read request body.")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == contentTypeMsgpack {
+		err = unmarshalMsgpack(data, body)
+		if err != nil {
+			return &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_msgpack",
+				Message: "Request body is not valid msgpack.",
+				Details: err.Error(),
+			}
+		}
+		return nil
+	}
+
+	err = json.Unmarshal(data, body)
+	if err != nil {
+		return badJSON(err)
+	}
+	return nil
+}
+
+// writeSocket sends a message using the subprotocol the connection agreed on.
+func writeSocket(conn *websocket.Conn, body interface{}) error {
+	if conn.Subprotocol() == socketProtocolMsgpack {
+		data, err := marshalMsgpack(body)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.BinaryMessage, data)
+	}
+	return conn.WriteJSON(body)
+}
+
+// readSocket reads the next message from a client, decoded using the
+// connection's subprotocol. A message that can't be decoded returns an
+// APIError; a failed read returns the read error.
+func readSocket(conn *websocket.Conn, body interface{}) (*APIError, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if conn.Subprotocol() == socketProtocolMsgpack {
+		err = unmarshalMsgpack(data, body)
+		if err != nil {
+			return &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_msgpack",
+				Message: "Message is not valid msgpack.",
+				Details: err.Error(),
+			}, nil
+		}
+		return nil, nil
+	}
+	err = json.Unmarshal(data, body)
+	if err != nil {
+		return badJSON(err), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// sockets opened with ?compact=true are sent move_diff events in place of
+// move_made
+var eventMoveDiff = "move_diff"
+
+// MoveDiffPayload is a move as only the squares it changed, including the
+// rook of a castle and a pawn taken en passant. A client applies the diffs
+// to its board and compares positionHash to check it is still in sync; if
+// not, it can subscribe again to get a snapshot.
+type MoveDiffPayload struct {
+	Ply          int          `json:"ply"`
+	SquareDiffs  []squareDiff `json:"squareDiffs"`
+	UCI          string       `json:"uci"`
+	PositionHash string       `json:"positionHash"`
+}
+
+// positionHash is the first 16 hex digits of the sha256 of a board as
+// serializeBoard writes it, the same bytes players sign.
+func positionHash(board [8][8]int) string {
+	sum := sha256.Sum256(serializeBoard(board))
+	return hex.EncodeToString(sum[:8])
+}
+
+// compactEvent turns a move_made event into a move_diff one. Moves stored
+// before diffs were recorded are sent in full.
+func compactEvent(event EventEnvelope) EventEnvelope {
+	move, ok := event.Payload.(MoveMadePayload)
+	if !ok || move.UCI == "" {
+		return event
+	}
+	event.Type = eventMoveDiff
+	event.Payload = MoveDiffPayload{
+		Ply:          move.Ply,
+		SquareDiffs:  move.SquareDiffs,
+		UCI:          move.UCI,
+		PositionHash: move.PositionHash,
+	}
+	return event
+}
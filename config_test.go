@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(config *Config)
+		err    string
+	}{
+		{"defaults", func(*Config) {}, ""},
+		{"no ping interval", func(config *Config) { config.Socket.PingIntervalSeconds = 0 }, "socket.pingIntervalSeconds"},
+		{"no write timeout", func(config *Config) { config.Socket.WriteTimeoutSeconds = 0 }, "socket.writeTimeoutSeconds"},
+		{"no queue", func(config *Config) { config.Socket.QueueSize = 0 }, "socket.queueSize"},
+		{"no poll interval", func(config *Config) { config.Broadcast.PollMillis = 0 }, "broadcast.pollMillis"},
+		{"no idle timeout", func(config *Config) { config.GameIdleTimeout = 0 }, "gameIdleTimeout"},
+		{"no connections", func(config *Config) { config.Socket.MaxConnectionsPerIP = 0 }, "socket.maxConnectionsPerIP"},
+		{"no replay", func(config *Config) { config.Socket.MaxReplayEvents = 0 }, ""},
+		{"negative replay", func(config *Config) { config.Socket.MaxReplayEvents = -1 }, "socket.maxReplayEvents"},
+		{"pong before ping", func(config *Config) { config.Socket.PongTimeoutSeconds = 30 }, "pongTimeoutSeconds"},
+		{"trusted proxies", func(config *Config) { config.TrustedProxies = []string{"10.0.0.1", "fd00::/8"} }, ""},
+		{"bad trusted proxy", func(config *Config) { config.TrustedProxies = []string{"proxy.local"} }, "trustedProxies"},
+		{"unknown backplane", func(config *Config) { config.Broadcast.Backplane = "redis" }, "broadcast.backplane"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := defaultConfig
+			test.change(&config)
+			err := config.validate()
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got %v, want error about %s", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// GameOptions is a request to the POST /game endpoint. Every field is
+// optional; an empty body creates a standard, untimed, casual public game.
+type GameOptions struct {
+	// PubKey, Nonce and Signed seat the creator straight away. Nonce comes
+	// from POST /game/nonce and Signed is the creator's signature of
+	// "create:<nonce>:<pubKey>".
+	PubKey           string `json:"pubKey"`
+	Nonce            string `json:"nonce"`
+	Signed           string `json:"signed"`
+	Color            string `json:"color"`
+	InitialSeconds   int    `json:"initialSeconds"`
+	IncrementSeconds int    `json:"incrementSeconds"`
+	Variant          string `json:"variant"`
+	StartFEN         string `json:"startFen"`
+	Rated            bool   `json:"rated"`
+	Visibility       string `json:"visibility"`
+}
+
+var standardFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var variantStandard = "standard"
+var variantFromPosition = "fromPosition"
+
+var visibilityPublic = "public"
+var visibilityPrivate = "private"
+
+// longest clock a side can start with, and the largest increment
+var maxInitialSeconds = 3 * 60 * 60
+var maxIncrementSeconds = 180
+
+// startingPosition is the position a game with the given start FEN begins
+// from. Games without one start from the standard position.
+func startingPosition(fen string) (Position, error) {
+	if fen == "" {
+		fen = standardFEN
+	}
+	return parseFEN(fen)
+}
+
+// validate fills in defaults and checks the options, returning the
+// creator's public key if one was given.
+func (options *GameOptions) validate() (ed25519.PublicKey, *APIError) {
+	if options.Variant == "" {
+		options.Variant = variantStandard
+		if options.StartFEN != "" {
+			options.Variant = variantFromPosition
+		}
+	}
+	switch options.Variant {
+	case variantStandard:
+		if options.StartFEN != "" && options.StartFEN != standardFEN {
+			return nil, newAPIError(http.StatusBadRequest, "invalid_start_fen", "Standard games can not have a custom starting position.")
+		}
+		options.StartFEN = ""
+	case variantFromPosition:
+		if options.StartFEN == "" {
+			return nil, newAPIError(http.StatusBadRequest, "invalid_start_fen", "fromPosition games need a startFen.")
+		}
+		_, err := parseFEN(options.StartFEN)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, "invalid_start_fen", "Starting position is not valid: "+err.Error()+".")
+		}
+	default:
+		return nil, newAPIError(http.StatusUnprocessableEntity, "unsupported_variant", "Variant must be standard or fromPosition.")
+	}
+
+	if options.Rated && options.Variant != variantStandard {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_rated", "Only standard games can be rated.")
+	}
+
+	if options.InitialSeconds < 0 || options.InitialSeconds > maxInitialSeconds {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_time_control", "initialSeconds must be between 0 and "+strconv.Itoa(maxInitialSeconds)+".")
+	}
+	if options.IncrementSeconds < 0 || options.IncrementSeconds > maxIncrementSeconds {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_time_control", "incrementSeconds must be between 0 and "+strconv.Itoa(maxIncrementSeconds)+".")
+	}
+	if options.InitialSeconds == 0 && options.IncrementSeconds != 0 {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_time_control", "Untimed games can not have an increment.")
+	}
+
+	switch options.Visibility {
+	case "":
+		options.Visibility = visibilityPublic
+	case visibilityPublic, visibilityPrivate:
+	default:
+		return nil, newAPIError(http.StatusBadRequest, "invalid_visibility", "Visibility must be public or private.")
+	}
+
+	if options.PubKey == "" {
+		if options.Color != "" {
+			return nil, newAPIError(http.StatusBadRequest, "invalid_color", "Color can only be chosen together with pubKey.")
+		}
+		return nil, nil
+	}
+
+	switch options.Color {
+	case "", "RANDOM":
+		n, err := rand.Int(rand.Reader, big.NewInt(2))
+		check(err)
+		options.Color = "WHITE"
+		if n.Int64() == 1 {
+			options.Color = "BLACK"
+		}
+	case "WHITE", "BLACK":
+	default:
+		return nil, newAPIError(http.StatusBadRequest, "invalid_color", "Color must be WHITE, BLACK or RANDOM.")
+	}
+
+	pubKey, err := hex.DecodeString(options.PubKey)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_public_key", "Public key is not valid hex string.")
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_public_key", "Public key is the wrong length.")
+	}
+	sig, err := hex.DecodeString(options.Signed)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_signature_encoding", "Signature is not valid hex string.")
+	}
+	if !ed25519.Verify(pubKey, createMessage(options.Nonce, options.PubKey), sig) {
+		return nil, newAPIError(http.StatusUnauthorized, "invalid_signature", "Signature didn't verify properly.")
+	}
+	if !useCreateNonce(options.Nonce) {
+		return nil, newAPIError(http.StatusUnauthorized, "invalid_nonce", "Nonce is unknown, used or expired.")
+	}
+	return pubKey, nil
+}
+
+// GamePostHandler handles the game endpoint.
+func GamePostHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+
+		options := GameOptions{}
+		if req.ContentLength != 0 {
+			apiErr := readBody(req, &options)
+			if apiErr != nil {
+				writeError(res, req, apiErr)
+				return
+			}
+		}
+
+		game, apiErr := createGame(options)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		writeJSON(res, req, game)
+	})
+}
+
+func createGame(options GameOptions) (Game, *APIError) {
+	pubKey, apiErr := options.validate()
+	if apiErr != nil {
+		return Game{}, apiErr
+	}
+	start, err := startingPosition(options.StartFEN)
+	check(err)
+
+	game := Game{
+		GameID:           uuid.NewV4(),
+		Variant:          options.Variant,
+		StartFEN:         options.StartFEN,
+		InitialSeconds:   options.InitialSeconds,
+		IncrementSeconds: options.IncrementSeconds,
+		Rated:            options.Rated,
+		Visibility:       options.Visibility,
+	}
+	if options.Color == "WHITE" {
+		game.WhitePlayer = pubKey
+	}
+	if options.Color == "BLACK" {
+		game.BlackPlayer = pubKey
+	}
+	check(insertGame(db, &game))
+	game.JoinLink = joinLink(game)
+	firstState := startingBoardState(game.GameID, start)
+	db.Create(&firstState)
+	return game, nil
+}
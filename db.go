@@ -14,20 +14,70 @@ type Config struct {
 	DbType          string `json:"dbType"`
 	DbConnectionStr string `json:"dbConnectionStr"`
 	Port            int    `json:"port"`
+	GrpcPort        int    `json:"grpcPort"`
+	GameIdleTimeout int    `json:"gameIdleTimeout"`
+	JoinLinkFormat  string `json:"joinLinkFormat"`
+	// origins of pages allowed to use the api and open websockets, besides
+	// our own; "*" allows any
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// addresses or cidr ranges of proxies whose X-Forwarded-For is believed
+	// when counting connections per address
+	TrustedProxies []string        `json:"trustedProxies"`
+	Socket         SocketConfig    `json:"socket"`
+	Broadcast      BroadcastConfig `json:"broadcast"`
+	Retention      RetentionConfig `json:"retention"`
 }
 
 var defaultConfig = Config{
 	DbType:          "sqlite3",
 	DbConnectionStr: "chess.db",
 	Port:            8000,
+	GameIdleTimeout: 600,
+	AllowedOrigins:  []string{},
+	TrustedProxies:  []string{},
+	Socket: SocketConfig{
+		PingIntervalSeconds:   30,
+		PongTimeoutSeconds:    60,
+		WriteTimeoutSeconds:   10,
+		QueueSize:             64,
+		MaxReplayEvents:       200,
+		MaxConnectionsPerIP:   20,
+		MaxConnectionsPerGame: 500,
+	},
+	Broadcast: BroadcastConfig{
+		Backplane:      backplaneLocal,
+		PollMillis:     200,
+		LookbackMillis: 5000,
+	},
+	// retention only runs once an interval is set
+	Retention: RetentionConfig{
+		IntervalMinutes:     0,
+		UnjoinedGameHours:   24,
+		ArchiveFinishedDays: 30,
+		ArchiveDir:          "archive",
+	},
 }
 
 // Game is an individual chess game.
 type Game struct {
 	Model
-	GameID      uuid.UUID         `json:"gameID"`
-	WhitePlayer ed25519.PublicKey `json:"whitePlayer"`
-	BlackPlayer ed25519.PublicKey `json:"blackPlayer"`
+	GameID           uuid.UUID         `json:"gameID"`
+	Code             string            `json:"code" gorm:"unique_index"`
+	WhitePlayer      ed25519.PublicKey `json:"whitePlayer"`
+	BlackPlayer      ed25519.PublicKey `json:"blackPlayer"`
+	Variant          string            `json:"variant"`
+	StartFEN         string            `json:"startFen,omitempty"`
+	InitialSeconds   int               `json:"initialSeconds"`
+	IncrementSeconds int               `json:"incrementSeconds"`
+	Rated            bool              `json:"rated"`
+	Visibility       string            `json:"visibility"`
+	Result           string            `json:"result,omitempty"`
+	ResultReason     string            `json:"resultReason,omitempty"`
+	DrawOffer        string            `json:"drawOffer,omitempty"`
+	JoinLink         string            `json:"joinLink,omitempty" gorm:"-"`
+	// bumped by every change, so an instance can tell whether the game has
+	// changed since it read it
+	Revision int `json:"-"`
 }
 
 // BoardState is a single moment in time for a chess board
@@ -42,6 +92,10 @@ type BoardState struct {
 	EndPosition   string    `json:"endPos"`
 	Check         bool      `json:"check"`
 	CheckMate     bool      `json:"checkMate"`
+	SignedState   []byte    `json:"signedState"`
+	Signature     string    `json:"signature"`
+	// 0 for the starting position; unique within a game
+	Ply int `json:"-"`
 }
 
 // ReceivedBoardState is a new board state received from the client.
@@ -59,6 +113,34 @@ type Model struct {
 	DeletedAt *time.Time `json:"-" sql:"index"`
 }
 
+// backfillPlies numbers the board states of games stored before board
+// states had a ply.
+func backfillPlies(db *gorm.DB) {
+	gameIDs := []uuid.UUID{}
+	db.Unscoped().Model(&BoardState{}).Group("game_id").Having("COUNT(*) > 1 AND MAX(ply) = 0").Pluck("game_id", &gameIDs)
+	for _, gameID := range gameIDs {
+		ids := []uint{}
+		db.Unscoped().Model(&BoardState{}).Where("game_id = ?", gameID).Order("id").Pluck("id", &ids)
+		for ply, id := range ids {
+			db.Unscoped().Model(&BoardState{}).Where("id = ?", id).UpdateColumn("ply", ply)
+		}
+	}
+}
+
+// renumberEvents numbers the events of games that two instances gave the
+// same sequence before sequences were unique, in the order they were stored.
+func renumberEvents(db *gorm.DB) {
+	gameIDs := []uuid.UUID{}
+	db.Unscoped().Model(&StoredEvent{}).Group("game_id").Having("COUNT(*) > COUNT(DISTINCT sequence)").Pluck("game_id", &gameIDs)
+	for _, gameID := range gameIDs {
+		ids := []uint{}
+		db.Unscoped().Model(&StoredEvent{}).Where("game_id = ?", gameID).Order("id").Pluck("id", &ids)
+		for i, id := range ids {
+			db.Unscoped().Model(&StoredEvent{}).Where("id = ?", id).UpdateColumn("sequence", i+1)
+		}
+	}
+}
+
 func fileExists(filename string) bool {
 	_, fileErr := os.Stat(filename)
 	if os.IsNotExist(fileErr) {
@@ -74,6 +156,25 @@ func getDB(config Config) *gorm.DB {
 
 	db.AutoMigrate(Game{})
 	db.AutoMigrate(BoardState{})
+	db.AutoMigrate(StoredEvent{})
+	db.AutoMigrate(CreateNonce{})
+	db.AutoMigrate(NodePresence{})
+
+	// instances sharing a database rely on these to notice they raced
+	if !db.Dialect().HasIndex("board_states", "idx_board_states_game_ply") {
+		backfillPlies(db)
+		check(db.Model(BoardState{}).AddUniqueIndex("idx_board_states_game_ply", "game_id", "ply").Error)
+	}
+	if !db.Dialect().HasIndex("stored_events", "idx_stored_events_game_sequence") {
+		renumberEvents(db)
+		check(db.Model(StoredEvent{}).AddUniqueIndex("idx_stored_events_game_sequence", "game_id", "sequence").Error)
+	}
+	db.Model(StoredEvent{}).AddIndex("idx_stored_events_created_at", "created_at")
+
+	// games from before creation options were standard and public
+	db.Model(Game{}).Where("variant IS NULL OR variant = ''").UpdateColumn("variant", variantStandard)
+	db.Model(Game{}).Where("visibility IS NULL OR visibility = ''").UpdateColumn("visibility", visibilityPublic)
+	backfillGameCodes(db)
 
 	return db
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// APIError is the body sent back for every failed request.
+type APIError struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func newAPIError(status int, code string, message string) *APIError {
+	return &APIError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+var errGameNotFound = newAPIError(http.StatusNotFound, "game_not_found", "Game not found.")
+var errInternal = newAPIError(http.StatusInternalServerError, "internal_error", "Something went wrong on the server.")
+var errInvalidGameID = newAPIError(http.StatusBadRequest, "invalid_game_id", "Game ID is not a valid uuid or game code.")
+
+func badJSON(err error) *APIError {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_json",
+		Message: "Request body is not valid json.",
+		Details: err.Error(),
+	}
+}
+
+func writeError(res http.ResponseWriter, req *http.Request, apiErr *APIError) {
+	fmt.Println(apiErr.Message)
+	writeBody(res, req, apiErr.Status, apiErr)
+}
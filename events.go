@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// StoredEvent is a game event kept so reconnecting clients can catch up.
+type StoredEvent struct {
+	Model
+	GameID uuid.UUID `gorm:"index"`
+	// the instance that published it
+	Node     string
+	Sequence int
+	Type     string
+	Payload  []byte
+}
+
+// EventEnvelope wraps every notification about a game. Events for a game
+// are numbered from 1 in the order they happened. The payload depends on
+// the type; schema/events.schema.json describes them all.
+type EventEnvelope struct {
+	Version   int         `json:"version"`
+	Type      string      `json:"type"`
+	GameID    uuid.UUID   `json:"gameID"`
+	Sequence  int         `json:"sequence"`
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload"`
+}
+
+var eventEnvelopeVersion = 1
+
+var eventPlayerJoined = "player_joined"
+var eventMoveMade = "move_made"
+var eventCheck = "check"
+var eventGameOver = "game_over"
+var eventDrawOffered = "draw_offered"
+var eventClockUpdate = "clock_update"
+var eventChat = "chat"
+var eventSnapshot = "snapshot"
+var eventPresence = "presence"
+
+// PlayerJoinedPayload is sent when a player takes a side.
+type PlayerJoinedPayload struct {
+	Side string `json:"side"`
+	Game Game   `json:"game"`
+}
+
+// MoveMadePayload is sent for every accepted move. The diffs, UCI move and
+// hash are what compact sockets get instead; see MoveDiffPayload.
+type MoveMadePayload struct {
+	Ply          int          `json:"ply"`
+	Board        [8][8]int    `json:"board"`
+	BoardState   BoardState   `json:"boardState"`
+	SquareDiffs  []squareDiff `json:"squareDiffs,omitempty"`
+	UCI          string       `json:"uci,omitempty"`
+	PositionHash string       `json:"positionHash,omitempty"`
+}
+
+// CheckPayload is sent when a move leaves the other side in check.
+type CheckPayload struct {
+	Ply  int    `json:"ply"`
+	Side string `json:"side"`
+}
+
+// GameOverPayload is sent once a game has a result. Side is the player
+// whose move or action ended it.
+type GameOverPayload struct {
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+	Side   string `json:"side"`
+}
+
+// DrawOfferedPayload is sent when a player offers a draw.
+type DrawOfferedPayload struct {
+	Side string `json:"side"`
+}
+
+// ClockUpdatePayload is the time left for each side as of Since. Running
+// is the side whose clock is counting down, if any.
+type ClockUpdatePayload struct {
+	WhiteMillis int64     `json:"whiteMillis"`
+	BlackMillis int64     `json:"blackMillis"`
+	Running     string    `json:"running,omitempty"`
+	Since       time.Time `json:"since"`
+}
+
+// ChatPayload is a chat message from a player.
+type ChatPayload struct {
+	Side string `json:"side"`
+	Text string `json:"text"`
+}
+
+// SnapshotPayload is sent instead of a replay when a client is too far
+// behind. It replaces everything the client knew about the game.
+type SnapshotPayload struct {
+	Game       Game                `json:"game"`
+	LastPly    int                 `json:"lastPly"`
+	Board      [8][8]int           `json:"board"`
+	BoardState BoardState          `json:"boardState"`
+	Clock      *ClockUpdatePayload `json:"clock,omitempty"`
+}
+
+// eventPayloads makes an empty payload for each event type, for decoding
+// stored events.
+var eventPayloads = map[string]func() interface{}{
+	eventPlayerJoined: func() interface{} { return &PlayerJoinedPayload{} },
+	eventMoveMade:     func() interface{} { return &MoveMadePayload{} },
+	eventCheck:        func() interface{} { return &CheckPayload{} },
+	eventGameOver:     func() interface{} { return &GameOverPayload{} },
+	eventDrawOffered:  func() interface{} { return &DrawOfferedPayload{} },
+	eventClockUpdate:  func() interface{} { return &ClockUpdatePayload{} },
+	eventChat:         func() interface{} { return &ChatPayload{} },
+	eventPresence:     func() interface{} { return &PresencePayload{} },
+}
+
+func lastSequence(gameID uuid.UUID) int {
+	var sequence int
+	row := db.Model(&StoredEvent{}).Where("game_id = ?", gameID).Select("COALESCE(MAX(sequence), 0)").Row()
+	check(row.Scan(&sequence))
+	return sequence
+}
+
+// storeEvent stores an event, failing if the game already has an event with
+// its sequence.
+func storeEvent(event EventEnvelope) error {
+	payload, err := json.Marshal(event)
+	check(err)
+	return db.Create(&StoredEvent{
+		GameID:   event.GameID,
+		Node:     nodeID,
+		Sequence: event.Sequence,
+		Type:     event.Type,
+		Payload:  payload,
+	}).Error
+}
+
+// decodeEvent reads a stored event back into its envelope. Events stored
+// in another envelope version can't be decoded.
+func decodeEvent(stored StoredEvent) (EventEnvelope, bool) {
+	newPayload, ok := eventPayloads[stored.Type]
+	if !ok {
+		return EventEnvelope{}, false
+	}
+	var raw struct {
+		EventEnvelope
+		Payload json.RawMessage `json:"payload"`
+	}
+	err := json.Unmarshal(stored.Payload, &raw)
+	if err != nil || raw.Version != eventEnvelopeVersion {
+		return EventEnvelope{}, false
+	}
+	payload := newPayload()
+	err = json.Unmarshal(raw.Payload, payload)
+	if err != nil {
+		return EventEnvelope{}, false
+	}
+	event := raw.EventEnvelope
+	// the row's sequence is the one that counts if the event was renumbered
+	event.Sequence = stored.Sequence
+	// payloads are passed around by value, the same as when broadcast
+	event.Payload = reflect.ValueOf(payload).Elem().Interface()
+	return event, true
+}
+
+// replayEvents loads the events after lastSeen. It returns false if they
+// can't all be replayed, in which case the client needs a snapshot.
+func replayEvents(gameID uuid.UUID, lastSeen int) ([]EventEnvelope, bool) {
+	stored := []StoredEvent{}
+	db.Where("game_id = ? AND sequence > ?", gameID, lastSeen).Order("sequence").Limit(config.Socket.MaxReplayEvents + 1).Find(&stored)
+	if len(stored) > config.Socket.MaxReplayEvents {
+		return nil, false
+	}
+	events := []EventEnvelope{}
+	for i, row := range stored {
+		event, ok := decodeEvent(row)
+		if !ok || event.Sequence != lastSeen+i+1 {
+			return nil, false
+		}
+		events = append(events, event)
+	}
+	return events, true
+}
+
+func snapshotEvent(actor *gameActor) EventEnvelope {
+	payload := SnapshotPayload{
+		Game:       actor.game,
+		LastPly:    actor.history.lastPly,
+		Board:      deserializeBoard(actor.history.lastMove.State),
+		BoardState: actor.history.lastMove,
+	}
+	if actor.clock.timed {
+		clock := actor.clock.payload(nextMoveAuthor(actor.history.lastMove))
+		payload.Clock = &clock
+	}
+	return EventEnvelope{
+		Version:   eventEnvelopeVersion,
+		Type:      eventSnapshot,
+		GameID:    actor.game.GameID,
+		Sequence:  actor.sequence,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+}
+
+// subscribeEvents sends a socket the events after lastSeen. It runs on the
+// actor, so no new events can happen while it does. If the socket has
+// already been sent newer events, or the gap can't be replayed, it gets a
+// snapshot instead.
+func subscribeEvents(actor *gameActor, sub *SocketSub, lastSeen int) (int, *APIError) {
+	if lastSeen < 0 || lastSeen > actor.sequence {
+		return 0, newAPIError(http.StatusBadRequest, "invalid_sequence", "Last sequence must be between 0 and the game's latest sequence.")
+	}
+
+	audience := sub.getAudience()
+	events, ok := replayEvents(actor.game.GameID, lastSeen)
+	if !ok || sub.queuedEvents() && lastSeen < actor.sequence {
+		sub.skipEventsThrough(actor.sequence)
+		snapshot, ok := viewEvent(actor.game, audience, snapshotEvent(actor))
+		if ok {
+			sub.reply(snapshot)
+		}
+		return actor.sequence, nil
+	}
+	sub.skipEventsThrough(lastSeen)
+	for _, event := range viewEvents(actor.game, audience, events) {
+		if sub.compact {
+			event = compactEvent(event)
+		}
+		sub.reply(event)
+	}
+	return actor.sequence, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ExportHeader is the first line of an export archive.
+type ExportHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+var exportFormat = "chess-export"
+
+func exportCommand(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("out", "", "file to write the archive to (default stdout)")
+	flags.Parse(args)
+
+	writer := io.Writer(os.Stdout)
+	if *out != "" {
+		file, err := os.Create(*out)
+		check(err)
+		defer file.Close()
+		writer = file
+	}
+
+	count, err := exportGames(writer)
+	check(err)
+	fmt.Fprintln(os.Stderr, "Exported "+fmt.Sprint(count)+" games.")
+}
+
+func importCommand(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "", "file to read the archive from (default stdin)")
+	allowUnsigned := flags.Bool("allow-unsigned", false, "accept moves recorded before signatures were stored")
+	flags.Parse(args)
+
+	reader := io.Reader(os.Stdin)
+	if *in != "" {
+		file, err := os.Open(*in)
+		check(err)
+		defer file.Close()
+		reader = file
+	}
+
+	imported, skipped, err := importGames(reader, *allowUnsigned)
+	check(err)
+	fmt.Fprintln(os.Stderr, "Imported "+fmt.Sprint(imported)+" games, skipped "+fmt.Sprint(skipped)+".")
+}
+
+// exportGames writes every game as one json line after the header.
+func exportGames(writer io.Writer) (int, error) {
+	buffered := bufio.NewWriter(writer)
+	encoder := json.NewEncoder(buffered)
+
+	err := encoder.Encode(ExportHeader{Format: exportFormat, Version: gameArchiveVersion})
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.Model(&Game{}).Order("id").Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		game := Game{}
+		err = db.ScanRows(rows, &game)
+		if err != nil {
+			return count, err
+		}
+		archive, ok := loadGameArchive(game.GameID)
+		if !ok {
+			continue
+		}
+		err = encoder.Encode(archive)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, buffered.Flush()
+}
+
+// importGames reads an export archive, replaying each game through the rules
+// before storing it. Games that already exist or fail verification are skipped.
+func importGames(reader io.Reader, allowUnsigned bool) (int, int, error) {
+	decoder := json.NewDecoder(reader)
+
+	header := ExportHeader{}
+	err := decoder.Decode(&header)
+	if err != nil {
+		return 0, 0, err
+	}
+	if header.Format != exportFormat || header.Version < 1 || header.Version > gameArchiveVersion {
+		return 0, 0, fmt.Errorf("unsupported archive %s version %d", header.Format, header.Version)
+	}
+
+	imported := 0
+	skipped := 0
+	for {
+		archive := GameArchive{}
+		err = decoder.Decode(&archive)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, skipped, err
+		}
+
+		if !db.First(&Game{}, "game_id = ?", archive.Game.GameID).RecordNotFound() {
+			fmt.Fprintln(os.Stderr, "Game "+archive.Game.GameID.String()+" already exists.")
+			skipped++
+			continue
+		}
+		err = verifyGameArchive(archive, allowUnsigned)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Game "+archive.Game.GameID.String()+" failed verification: "+err.Error())
+			skipped++
+			continue
+		}
+
+		err = restoreGameArchive(archive)
+		if err != nil {
+			return imported, skipped, err
+		}
+		imported++
+	}
+
+	return imported, skipped, nil
+}
+
+// restoreGameArchive stores a verified game with its original timestamps,
+// all or nothing.
+func restoreGameArchive(archive GameArchive) error {
+	tx := db.Begin()
+	game := archive.Game
+	game.CreatedAt = archive.CreatedAt
+	game.UpdatedAt = archive.UpdatedAt
+	// keep the archived code unless another game has taken it
+	err := insertGame(tx, &game)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i, boardState := range archive.BoardStates {
+		boardState.ID = 0
+		boardState.Ply = i
+		if len(archive.MoveTimes) != 0 {
+			boardState.CreatedAt = archive.MoveTimes[i]
+			boardState.UpdatedAt = archive.MoveTimes[i]
+		}
+		err = tx.Create(&boardState).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, event := range archive.Events {
+		err = tx.Create(&StoredEvent{
+			Model:    Model{CreatedAt: event.CreatedAt, UpdatedAt: event.CreatedAt},
+			GameID:   game.GameID,
+			Node:     nodeID,
+			Sequence: event.Sequence,
+			Type:     event.Type,
+			Payload:  event.Payload,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// submittedBoard rebuilds the board a player sent for a move stored without
+// it, by undoing what the server added: the rook's half of a castle and the
+// removal of a pawn taken en passant.
+func submittedBoard(boardState BoardState) [8][8]int {
+	board := deserializeBoard(boardState.State)
+	if len(boardState.StartPosition) != 2 || len(boardState.EndPosition) != 2 {
+		return board
+	}
+	startPos := stringToPos(boardState.StartPosition)
+	endPos := stringToPos(boardState.EndPosition)
+	if !locWithinBounds(startPos) || !locWithinBounds(endPos) {
+		return board
+	}
+	row := startPos[0]
+	switch {
+	case (boardState.PieceMoved == whiteKing || boardState.PieceMoved == blackKing) && endPos[1]-startPos[1] == 2:
+		board[row][7], board[row][5] = board[row][5], empty
+	case (boardState.PieceMoved == whiteKing || boardState.PieceMoved == blackKing) && startPos[1]-endPos[1] == 2:
+		board[row][0], board[row][3] = board[row][3], empty
+	case boardState.PieceMoved == whitePawn && startPos[1] != endPos[1] && boardState.PieceTaken == 0:
+		board[row][endPos[1]] = blackPawn
+	case boardState.PieceMoved == blackPawn && startPos[1] != endPos[1] && boardState.PieceTaken == 0:
+		board[row][endPos[1]] = whitePawn
+	}
+	return board
+}
+
+func verifyGameArchive(archive GameArchive, allowUnsigned bool) error {
+	if len(archive.BoardStates) == 0 {
+		return fmt.Errorf("game has no board states")
+	}
+	start, err := startingPosition(archive.Game.StartFEN)
+	if err != nil {
+		return err
+	}
+	first := archive.BoardStates[0]
+	expected := startingBoardState(archive.Game.GameID, start)
+	if first.MoveAuthor != expected.MoveAuthor || !bytes.Equal(first.State, expected.State) {
+		return fmt.Errorf("game does not start from its starting position")
+	}
+
+	if len(archive.MoveTimes) != 0 && len(archive.MoveTimes) != len(archive.BoardStates) {
+		return fmt.Errorf("game has %d move times for %d board states", len(archive.MoveTimes), len(archive.BoardStates))
+	}
+	for i, event := range archive.Events {
+		if i > 0 && event.Sequence <= archive.Events[i-1].Sequence {
+			return fmt.Errorf("event %d is out of order", event.Sequence)
+		}
+	}
+
+	history := newMoveHistory(archive.Game)
+	history.record(first)
+
+	for i, boardState := range archive.BoardStates[1:] {
+		if boardState.GameID != archive.Game.GameID {
+			return fmt.Errorf("move %d belongs to another game", i+1)
+		}
+		signedState := boardState.SignedState
+		if len(signedState) == 0 {
+			if !allowUnsigned {
+				return fmt.Errorf("move %d has no signature", i+1)
+			}
+			signedState = serializeBoard(submittedBoard(boardState))
+		} else {
+			playerKey := sideKey(archive.Game, boardState.MoveAuthor)
+			sig, err := hex.DecodeString(boardState.Signature)
+			if err != nil || len(playerKey) != ed25519.PublicKeySize || !ed25519.Verify(playerKey, signedState, sig) {
+				return fmt.Errorf("move %d has an invalid signature", i+1)
+			}
+		}
+
+		replayed, result := playMove(archive.Game.GameID, history.lastMove, deserializeBoard(signedState), history)
+		if !result.Valid {
+			return fmt.Errorf("move %d is not legal: %s", i+1, result.Violation)
+		}
+		if replayed.MoveAuthor != boardState.MoveAuthor || !bytes.Equal(replayed.State, boardState.State) {
+			return fmt.Errorf("move %d does not match the recorded position", i+1)
+		}
+		history.record(boardState)
+	}
+
+	return nil
+}
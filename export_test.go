@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// testArchive plays e4 e5 Nf3 as a signed game.
+func testArchive(t *testing.T) (GameArchive, ed25519.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+	return testArchiveFrom(t, "", [][2]string{{"E2", "E4"}, {"E7", "E5"}, {"G1", "F3"}})
+}
+
+// testArchiveFrom plays moves as a signed game from fen, or from the standard
+// starting position if fen is empty.
+func testArchiveFrom(t *testing.T, fen string, moves [][2]string) (GameArchive, ed25519.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+	whiteKey, whitePriv, _ := ed25519.GenerateKey(nil)
+	blackKey, blackPriv, _ := ed25519.GenerateKey(nil)
+	game := Game{WhitePlayer: whiteKey, BlackPlayer: blackKey, Variant: variantStandard}
+	if fen == "" {
+		fen = startFEN
+	} else {
+		game.StartFEN = fen
+		game.Variant = variantFromPosition
+	}
+	gameID, history := testPosition(t, fen)
+	game.GameID = gameID
+	archive := GameArchive{
+		Version:     gameArchiveVersion,
+		Game:        game,
+		BoardStates: []BoardState{history.lastMove},
+		MoveTimes:   []time.Time{time.Now()},
+	}
+
+	keys := map[string]ed25519.PrivateKey{"WHITE": whitePriv, "BLACK": blackPriv}
+	for _, move := range moves {
+		submitted := submit(deserializeBoard(history.lastMove.State), move[0], move[1], 0)
+		boardState, result := playMove(gameID, history.lastMove, submitted, history)
+		if !result.Valid {
+			t.Fatalf("%s%s refused: %s", move[0], move[1], result.Violation)
+		}
+		boardState.Signature = hex.EncodeToString(ed25519.Sign(keys[boardState.MoveAuthor], boardState.SignedState))
+		history.record(boardState)
+		archive.BoardStates = append(archive.BoardStates, boardState)
+		archive.MoveTimes = append(archive.MoveTimes, time.Now())
+	}
+	return archive, whitePriv, blackPriv
+}
+
+// stripSignatures leaves an archive's moves the way they were stored before
+// moves were signed.
+func stripSignatures(archive *GameArchive, _, _ ed25519.PrivateKey) {
+	for i := range archive.BoardStates {
+		archive.BoardStates[i].SignedState = nil
+		archive.BoardStates[i].Signature = ""
+	}
+}
+
+func TestVerifyGameArchive(t *testing.T) {
+	tests := []struct {
+		name          string
+		fen           string
+		moves         [][2]string
+		tamper        func(archive *GameArchive, whitePriv ed25519.PrivateKey, blackPriv ed25519.PrivateKey)
+		allowUnsigned bool
+		err           string
+	}{
+		{
+			name:   "signed game",
+			tamper: func(*GameArchive, ed25519.PrivateKey, ed25519.PrivateKey) {},
+		},
+		{
+			name: "no board states",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.BoardStates = nil
+			},
+			err: "no board states",
+		},
+		{
+			name: "wrong starting position",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.Game.StartFEN = "4k3/8/8/8/8/8/8/4K3 w - - 0 1"
+			},
+			err: "starting position",
+		},
+		{
+			name: "move times don't match",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.MoveTimes = archive.MoveTimes[1:]
+			},
+			err: "move times",
+		},
+		{
+			name: "events out of order",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.Events = []ArchivedEvent{{Sequence: 2}, {Sequence: 2}}
+			},
+			err: "out of order",
+		},
+		{
+			name: "move from another game",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.BoardStates[2].GameID = uuid.NewV4()
+			},
+			err: "another game",
+		},
+		{
+			name: "unsigned move",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.BoardStates[1].SignedState = nil
+				archive.BoardStates[1].Signature = ""
+			},
+			err: "no signature",
+		},
+		{
+			name: "unsigned move allowed",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				archive.BoardStates[1].SignedState = nil
+				archive.BoardStates[1].Signature = ""
+			},
+			allowUnsigned: true,
+		},
+		{
+			name:          "unsigned castles allowed",
+			fen:           "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves:         [][2]string{{"E1", "G1"}, {"E8", "C8"}},
+			tamper:        stripSignatures,
+			allowUnsigned: true,
+		},
+		{
+			name:          "unsigned en passant allowed",
+			fen:           "4k3/3p4/8/4P3/8/8/8/4K3 b - - 0 1",
+			moves:         [][2]string{{"D7", "D5"}, {"E5", "D6"}},
+			tamper:        stripSignatures,
+			allowUnsigned: true,
+		},
+		{
+			name: "signed by the wrong player",
+			tamper: func(archive *GameArchive, _, blackPriv ed25519.PrivateKey) {
+				move := &archive.BoardStates[1]
+				move.Signature = hex.EncodeToString(ed25519.Sign(blackPriv, move.SignedState))
+			},
+			err: "invalid signature",
+		},
+		{
+			name: "recorded position doesn't follow",
+			tamper: func(archive *GameArchive, _, _ ed25519.PrivateKey) {
+				move := &archive.BoardStates[3]
+				board := submit(deserializeBoard(archive.BoardStates[2].State), "B1", "C3", 0)
+				move.State = serializeBoard(board)
+			},
+			err: "does not match",
+		},
+		{
+			name: "illegal move",
+			tamper: func(archive *GameArchive, whitePriv, _ ed25519.PrivateKey) {
+				move := &archive.BoardStates[3]
+				board := submit(deserializeBoard(archive.BoardStates[2].State), "G1", "G4", 0)
+				move.SignedState = serializeBoard(board)
+				move.State = move.SignedState
+				move.Signature = hex.EncodeToString(ed25519.Sign(whitePriv, move.SignedState))
+			},
+			err: "not legal",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			archive, whitePriv, blackPriv := testArchive(t)
+			if test.moves != nil {
+				archive, whitePriv, blackPriv = testArchiveFrom(t, test.fen, test.moves)
+			}
+			test.tamper(&archive, whitePriv, blackPriv)
+			err := verifyGameArchive(archive, test.allowUnsigned)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got %v, want error containing %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// gameCodeAlphabet leaves out 0, O, 1 and I so codes can be read aloud and
+// typed from a screen.
+var gameCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+var gameCodeLength = 7
+
+// how many codes to try before giving up on inserting a game
+var maxGameCodeAttempts = 10
+
+func newGameCode() string {
+	code := make([]byte, gameCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(gameCodeAlphabet))))
+		check(err)
+		code[i] = gameCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
+
+// insertGame creates game with a fresh code, drawing again if the code is
+// already taken. A code the game already has is kept if it is free.
+func insertGame(tx *gorm.DB, game *Game) error {
+	var err error
+	for attempt := 0; attempt < maxGameCodeAttempts; attempt++ {
+		if game.Code == "" || attempt > 0 {
+			game.Code = newGameCode()
+		}
+		err = tx.Create(game).Error
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// backfillGameCodes gives a code to games created before codes existed.
+func backfillGameCodes(db *gorm.DB) {
+	games := []Game{}
+	db.Where("code IS NULL OR code = ''").Find(&games)
+	for _, game := range games {
+		for attempt := 0; attempt < maxGameCodeAttempts; attempt++ {
+			if db.Model(&game).UpdateColumn("code", newGameCode()).Error == nil {
+				break
+			}
+		}
+	}
+}
+
+// resolveGameID accepts either a game's uuid or its short code, in any case.
+func resolveGameID(id string) (uuid.UUID, *APIError) {
+	gameID, err := uuid.FromString(id)
+	if err == nil {
+		return gameID, nil
+	}
+
+	code := strings.ToUpper(id)
+	if len(code) != gameCodeLength {
+		return gameID, errInvalidGameID
+	}
+	for _, char := range code {
+		if !strings.ContainsRune(gameCodeAlphabet, char) {
+			return gameID, errInvalidGameID
+		}
+	}
+
+	game := Game{}
+	if db.Select("game_id").Where("code = ?", code).First(&game).RecordNotFound() {
+		return gameID, errGameNotFound
+	}
+	return game.GameID, nil
+}
+
+// joinLink fills in config.JoinLinkFormat for a game, replacing {code} and
+// {id}. It is empty when no format is configured.
+func joinLink(game Game) string {
+	if config.JoinLinkFormat == "" {
+		return ""
+	}
+	return strings.NewReplacer("{code}", game.Code, "{id}", game.GameID.String()).Replace(config.JoinLinkFormat)
+}
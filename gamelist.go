@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GameSummary is one entry in a game listing.
+type GameSummary struct {
+	GameID       uuid.UUID `json:"gameID"`
+	Code         string    `json:"code"`
+	WhitePlayer  []byte    `json:"whitePlayer"`
+	BlackPlayer  []byte    `json:"blackPlayer"`
+	Status       string    `json:"status"`
+	Variant      string    `json:"variant"`
+	Rated        bool      `json:"rated"`
+	CreatedAt    time.Time `json:"createdAt"`
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// GameListResponse is a response to the /games endpoint.
+type GameListResponse struct {
+	Games  []GameSummary `json:"games"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+type gameListRow struct {
+	GameID       uuid.UUID
+	Code         string
+	WhitePlayer  []byte
+	BlackPlayer  []byte
+	Variant      string
+	Rated        bool
+	CreatedAt    time.Time
+	LastActivity time.Time
+	CheckMate    bool
+	Result       string
+}
+
+// games that were resigned or drawn have a result without a checkmate
+var gameInProgress = "(games.result IS NULL OR games.result = '')"
+
+var defaultGameListLimit = 20
+var maxGameListLimit = 100
+
+func gameStatus(row gameListRow) string {
+	if row.CheckMate || row.Result != "" {
+		return "finished"
+	}
+	if len(row.WhitePlayer) == 0 || len(row.BlackPlayer) == 0 {
+		return "open"
+	}
+	return "active"
+}
+
+// GameListHandler lists public games, most recently active first.
+func GameListHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+
+		params := req.URL.Query()
+		query := db.Table("games").
+			Joins("JOIN board_states latest ON latest.id = (SELECT MAX(id) FROM board_states WHERE board_states.game_id = games.game_id)").
+			Where("games.deleted_at IS NULL AND games.visibility = ?", visibilityPublic)
+
+		query, apiErr := filterGames(query, params.Get("player"), params.Get("side"), params.Get("status"), params.Get("variant"), params.Get("createdAfter"))
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+
+		limit, offset, apiErr := pageParams(params.Get("limit"), params.Get("offset"))
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+
+		total := 0
+		query.Count(&total)
+
+		rows := []gameListRow{}
+		query.Select("games.game_id, games.code, games.white_player, games.black_player, games.variant, games.rated, games.created_at, latest.created_at AS last_activity, latest.check_mate, games.result").
+			Order("last_activity DESC").
+			Limit(limit).
+			Offset(offset).
+			Scan(&rows)
+
+		response := GameListResponse{
+			Games:  []GameSummary{},
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		}
+		for _, row := range rows {
+			response.Games = append(response.Games, GameSummary{
+				GameID:       row.GameID,
+				Code:         row.Code,
+				WhitePlayer:  row.WhitePlayer,
+				BlackPlayer:  row.BlackPlayer,
+				Status:       gameStatus(row),
+				Variant:      row.Variant,
+				Rated:        row.Rated,
+				CreatedAt:    row.CreatedAt,
+				LastActivity: row.LastActivity,
+			})
+		}
+
+		writeJSON(res, req, response)
+	})
+}
+
+func filterGames(query *gorm.DB, player string, side string, status string, variant string, createdAfter string) (*gorm.DB, *APIError) {
+	if player != "" {
+		pubKey, err := hex.DecodeString(player)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, "invalid_public_key", "Player is not a valid hex public key.")
+		}
+		switch side {
+		case "":
+			query = query.Where("games.white_player = ? OR games.black_player = ?", pubKey, pubKey)
+		case "WHITE":
+			query = query.Where("games.white_player = ?", pubKey)
+		case "BLACK":
+			query = query.Where("games.black_player = ?", pubKey)
+		default:
+			return nil, newAPIError(http.StatusBadRequest, "invalid_side", "Side must be WHITE or BLACK.")
+		}
+	} else if side != "" {
+		return nil, newAPIError(http.StatusBadRequest, "invalid_side", "Side can only be used together with player.")
+	}
+
+	switch status {
+	case "":
+	case "open":
+		query = query.Where("latest.check_mate = ? AND "+gameInProgress+" AND (games.white_player IS NULL OR games.black_player IS NULL)", false)
+	case "active":
+		query = query.Where("latest.check_mate = ? AND "+gameInProgress+" AND games.white_player IS NOT NULL AND games.black_player IS NOT NULL", false)
+	case "finished":
+		query = query.Where("latest.check_mate = ? OR NOT "+gameInProgress, true)
+	default:
+		return nil, newAPIError(http.StatusBadRequest, "invalid_status", "Status must be open, active or finished.")
+	}
+
+	switch variant {
+	case "":
+	case variantStandard, variantFromPosition:
+		query = query.Where("games.variant = ?", variant)
+	default:
+		return nil, newAPIError(http.StatusBadRequest, "invalid_variant", "Variant must be standard or fromPosition.")
+	}
+
+	if createdAfter != "" {
+		after, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, "invalid_time", "createdAfter must be an RFC 3339 timestamp.")
+		}
+		query = query.Where("games.created_at > ?", after)
+	}
+
+	return query, nil
+}
+
+func pageParams(limitParam string, offsetParam string) (int, int, *APIError) {
+	limit := defaultGameListLimit
+	offset := 0
+	var err error
+	if limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxGameListLimit {
+			return 0, 0, newAPIError(http.StatusBadRequest, "invalid_limit", "Limit must be between 1 and "+strconv.Itoa(maxGameListLimit)+".")
+		}
+	}
+	if offsetParam != "" {
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return 0, 0, newAPIError(http.StatusBadRequest, "invalid_offset", "Offset must be a positive number.")
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,286 @@
+package main
+
+//go:generate protoc --proto_path=proto --go_out=chesspb --go_opt=paths=source_relative --go-grpc_out=chesspb --go-grpc_opt=paths=source_relative chess.proto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ExtraHash/chess/chesspb"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type grpcServer struct {
+	chesspb.UnimplementedChessServer
+}
+
+func grpcAPI() {
+	if config.GrpcPort == 0 {
+		return
+	}
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(config.GrpcPort))
+	check(err)
+
+	server := grpc.NewServer()
+	chesspb.RegisterChessServer(server, &grpcServer{})
+	fmt.Println("\nListening for grpc on port :" + strconv.Itoa(config.GrpcPort))
+	log.Fatal(server.Serve(listener))
+}
+
+func grpcError(apiErr *APIError) error {
+	code := codes.Internal
+	switch apiErr.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, apiErr.Message)
+}
+
+func parseGameID(id string) (uuid.UUID, error) {
+	gameID, apiErr := resolveGameID(id)
+	if apiErr != nil {
+		return gameID, grpcError(apiErr)
+	}
+	return gameID, nil
+}
+
+func boardToProto(board [8][8]int) *chesspb.Board {
+	squares := []int32{}
+	for _, row := range board {
+		for _, square := range row {
+			squares = append(squares, int32(square))
+		}
+	}
+	return &chesspb.Board{Squares: squares}
+}
+
+func boardFromProto(board *chesspb.Board) ([8][8]int, error) {
+	state := [8][8]int{}
+	if board == nil || len(board.Squares) != 64 {
+		return state, status.Error(codes.InvalidArgument, "Board must have 64 squares.")
+	}
+	for i, square := range board.Squares {
+		state[i/8][i%8] = int(square)
+	}
+	return state, nil
+}
+
+func gameToProto(game Game) *chesspb.Game {
+	return &chesspb.Game{
+		GameId:           game.GameID.String(),
+		Code:             game.Code,
+		JoinLink:         joinLink(game),
+		WhitePlayer:      game.WhitePlayer,
+		BlackPlayer:      game.BlackPlayer,
+		Variant:          game.Variant,
+		StartFen:         game.StartFEN,
+		InitialSeconds:   int32(game.InitialSeconds),
+		IncrementSeconds: int32(game.IncrementSeconds),
+		Rated:            game.Rated,
+		Visibility:       game.Visibility,
+	}
+}
+
+func boardStateToProto(boardState BoardState) *chesspb.BoardState {
+	return &chesspb.BoardState{
+		GameId:     boardState.GameID.String(),
+		Board:      boardToProto(deserializeBoard(boardState.State)),
+		MoveAuthor: boardState.MoveAuthor,
+		PieceMoved: int32(boardState.PieceMoved),
+		PieceTaken: int32(boardState.PieceTaken),
+		StartPos:   boardState.StartPosition,
+		EndPos:     boardState.EndPosition,
+		Check:      boardState.Check,
+		CheckMate:  boardState.CheckMate,
+	}
+}
+
+// eventToProto converts an event for WatchGame. Joins and moves carry their
+// game or board state; every event also carries its payload as json.
+func eventToProto(message interface{}) *chesspb.GameEvent {
+	event, ok := message.(EventEnvelope)
+	if !ok {
+		return nil
+	}
+	payloadJSON, err := json.Marshal(event.Payload)
+	check(err)
+	protoEvent := &chesspb.GameEvent{
+		Type:        event.Type,
+		Sequence:    int32(event.Sequence),
+		Timestamp:   event.Timestamp.UnixNano() / int64(time.Millisecond),
+		PayloadJson: string(payloadJSON),
+	}
+	switch payload := event.Payload.(type) {
+	case MoveMadePayload:
+		protoEvent.Payload = &chesspb.GameEvent_BoardState{BoardState: boardStateToProto(payload.BoardState)}
+	case PlayerJoinedPayload:
+		protoEvent.Payload = &chesspb.GameEvent_Game{Game: gameToProto(payload.Game)}
+	}
+	return protoEvent
+}
+
+func (server *grpcServer) CreateNonce(ctx context.Context, req *chesspb.CreateNonceRequest) (*chesspb.CreateNonceResponse, error) {
+	fmt.Println("grpc CreateNonce")
+	nonce := issueCreateNonce()
+	return &chesspb.CreateNonceResponse{Nonce: nonce.Nonce, ExpiresUnix: nonce.ExpiresAt.Unix()}, nil
+}
+
+func (server *grpcServer) CreateGame(ctx context.Context, req *chesspb.CreateGameRequest) (*chesspb.Game, error) {
+	fmt.Println("grpc CreateGame")
+	game, apiErr := createGame(GameOptions{
+		PubKey:           req.PubKey,
+		Nonce:            req.Nonce,
+		Signed:           req.Signed,
+		Color:            req.Color,
+		InitialSeconds:   int(req.InitialSeconds),
+		IncrementSeconds: int(req.IncrementSeconds),
+		Variant:          req.Variant,
+		StartFEN:         req.StartFen,
+		Rated:            req.Rated,
+		Visibility:       req.Visibility,
+	})
+	if apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+	return gameToProto(game), nil
+}
+
+func (server *grpcServer) JoinGame(ctx context.Context, req *chesspb.JoinGameRequest) (*chesspb.Game, error) {
+	fmt.Println("grpc JoinGame " + req.GameId)
+	gameID, err := parseGameID(req.GameId)
+	if err != nil {
+		return nil, err
+	}
+
+	joinRequest := JoinRequest{
+		PubKey: req.PubKey,
+		Signed: req.Signed,
+		Side:   req.Side,
+	}
+	var game Game
+	var apiErr *APIError
+	failure := withGame(gameID, func(actor *gameActor) {
+		game, apiErr = joinGame(actor, joinRequest)
+	})
+	if failure != nil {
+		return nil, grpcError(failure)
+	}
+	if apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+	return gameToProto(game), nil
+}
+
+func (server *grpcServer) MakeMove(ctx context.Context, req *chesspb.MoveRequest) (*chesspb.BoardState, error) {
+	fmt.Println("grpc MakeMove " + req.GameId)
+	gameID, err := parseGameID(req.GameId)
+	if err != nil {
+		return nil, err
+	}
+	state, err := boardFromProto(req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	move := ReceivedBoardState{
+		GameID: gameID,
+		State:  state,
+		Signed: req.Signed,
+	}
+	var newState BoardState
+	var apiErr *APIError
+	failure := withGame(gameID, func(actor *gameActor) {
+		newState, apiErr = makeMove(actor, move)
+	})
+	if failure != nil {
+		return nil, grpcError(failure)
+	}
+	if apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+	return boardStateToProto(newState), nil
+}
+
+func (server *grpcServer) GetGame(ctx context.Context, req *chesspb.GetGameRequest) (*chesspb.GameHistory, error) {
+	fmt.Println("grpc GetGame " + req.GameId)
+	gameID, err := parseGameID(req.GameId)
+	if err != nil {
+		return nil, err
+	}
+	if db.First(&Game{}, "game_id = ?", gameID).RecordNotFound() {
+		return nil, grpcError(errGameNotFound)
+	}
+
+	boardStates := []BoardState{}
+	db.Where("game_id = ?", gameID).Order("id").Find(&boardStates)
+
+	history := &chesspb.GameHistory{GameId: gameID.String()}
+	for _, boardState := range boardStates {
+		history.States = append(history.States, boardStateToProto(boardState))
+	}
+	return history, nil
+}
+
+func (server *grpcServer) WatchGame(req *chesspb.WatchGameRequest, stream chesspb.Chess_WatchGameServer) error {
+	fmt.Println("grpc WatchGame " + req.GameId)
+	gameID, err := parseGameID(req.GameId)
+	if err != nil {
+		return err
+	}
+	if db.First(&Game{}, "game_id = ?", gameID).RecordNotFound() {
+		return grpcError(errGameNotFound)
+	}
+
+	ip := ""
+	client, ok := peer.FromContext(stream.Context())
+	if ok {
+		ip = clientHost(client.Addr.String())
+	}
+	apiErr := reserveConnection(gameID, ip)
+	if apiErr != nil {
+		return grpcError(apiErr)
+	}
+	defer releaseConnection(gameID, ip)
+
+	watcher := watchGame(gameID)
+	defer unwatchGame(gameID, watcher)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case message, ok := <-watcher:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind, watch again to catch up")
+			}
+			event := eventToProto(message)
+			if event == nil {
+				continue
+			}
+			err = stream.Send(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	uuid "github.com/satori/go.uuid"
+)
+
+// SocketConfig controls websocket heartbeats and buffering.
+type SocketConfig struct {
+	// how often the server pings each connection
+	PingIntervalSeconds int `json:"pingIntervalSeconds"`
+	// how long a connection may go without a pong or message before it is dropped
+	PongTimeoutSeconds int `json:"pongTimeoutSeconds"`
+	// how long a single write may take
+	WriteTimeoutSeconds int `json:"writeTimeoutSeconds"`
+	// how many messages can wait to be written to one socket
+	QueueSize int `json:"queueSize"`
+	// how many missed events a reconnecting client can be sent before it
+	// gets a snapshot instead
+	MaxReplayEvents int `json:"maxReplayEvents"`
+	// how many websockets, event streams and grpc watches one address, and
+	// one game, may have open
+	MaxConnectionsPerIP   int `json:"maxConnectionsPerIP"`
+	MaxConnectionsPerGame int `json:"maxConnectionsPerGame"`
+}
+
+var socketSubs = map[uuid.UUID]map[*SocketSub]bool{}
+var socketSubsLock sync.Mutex
+
+// largest message a client may send us
+var socketReadLimit int64 = 4096
+
+// SocketSub is a websocket subscribed to a game. Only its write pump writes
+// to the connection.
+type SocketSub struct {
+	GameID uuid.UUID
+	IP     string
+	Conn   *websocket.Conn
+	send   chan interface{}
+	// why the server is closing the connection, sent in the close frame
+	closeCode   int
+	closeReason string
+	// whether any live event has been queued, and the last sequence the
+	// client already has
+	hasQueuedEvents bool
+	skipThrough     int
+	// who the client has proven to be, and what they must sign to prove it
+	audience  string
+	challenge string
+	// whether moves are sent as diffs
+	compact bool
+}
+
+// subscribeSocket subscribes a connection that reserveConnection has already
+// counted.
+func subscribeSocket(gameID uuid.UUID, ip string, conn *websocket.Conn, compact bool) *SocketSub {
+	sub := &SocketSub{
+		GameID:    gameID,
+		IP:        ip,
+		compact:   compact,
+		Conn:      conn,
+		send:      make(chan interface{}, config.Socket.QueueSize),
+		audience:  audienceSpectator,
+		challenge: newChallenge(),
+	}
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	if socketSubs[gameID] == nil {
+		socketSubs[gameID] = map[*SocketSub]bool{}
+	}
+	socketSubs[gameID][sub] = true
+	presenceChanged[gameID] = time.Now()
+	return sub
+}
+
+// unsubscribe removes the subscription and has its write pump close the
+// connection with the given code and reason. It is safe to call more than
+// once; the first reason wins.
+func (sub *SocketSub) unsubscribe(code int, reason string) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	sub.unsubscribeLocked(code, reason)
+}
+
+func (sub *SocketSub) unsubscribeLocked(code int, reason string) {
+	if !socketSubs[sub.GameID][sub] {
+		return
+	}
+	delete(socketSubs[sub.GameID], sub)
+	releaseConnectionLocked(sub.GameID, sub.IP)
+	presenceChanged[sub.GameID] = time.Now()
+	if len(socketSubs[sub.GameID]) == 0 {
+		delete(socketSubs, sub.GameID)
+		presenceEmptied = presenceChanged[sub.GameID]
+		delete(presenceChanged, sub.GameID)
+	}
+	// the actor can't be waited on while the lock is held
+	go withGame(sub.GameID, updatePresence)
+	sub.closeCode = code
+	sub.closeReason = reason
+	close(sub.send)
+}
+
+// writePump writes queued messages and pings until the subscription ends,
+// then sends a close frame and closes the connection.
+func (sub *SocketSub) writePump() {
+	writeTimeout := time.Duration(config.Socket.WriteTimeoutSeconds) * time.Second
+	ping := time.NewTicker(time.Duration(config.Socket.PingIntervalSeconds) * time.Second)
+	defer ping.Stop()
+	defer sub.Conn.Close()
+
+	for {
+		select {
+		case message, ok := <-sub.send:
+			if !ok {
+				fmt.Println("Closing websocket:", sub.closeCode, sub.closeReason)
+				sub.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(sub.closeCode, sub.closeReason), time.Now().Add(writeTimeout))
+				return
+			}
+			if event, ok := message.(EventEnvelope); ok && sub.stale(event) {
+				continue
+			}
+			sub.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err := writeSocket(sub.Conn, message)
+			if err != nil {
+				fmt.Println("Websocket write failed: " + err.Error())
+				sub.unsubscribe(websocket.CloseInternalServerErr, "write failed")
+				return
+			}
+		case <-ping.C:
+			err := sub.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			if err != nil {
+				sub.unsubscribe(websocket.CloseInternalServerErr, "ping failed")
+				return
+			}
+		}
+	}
+}
+
+// readPump handles requests from the client until it goes away or stops
+// answering pings, so dead connections are noticed and unsubscribed.
+func (sub *SocketSub) readPump() {
+	pongTimeout := time.Duration(config.Socket.PongTimeoutSeconds) * time.Second
+	sub.Conn.SetReadLimit(socketReadLimit)
+	sub.Conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	sub.Conn.SetPongHandler(func(string) error {
+		return sub.Conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var request SocketRequest
+		apiErr, err := readSocket(sub.Conn, &request)
+		if err != nil {
+			code, reason := closeStatus(err)
+			sub.unsubscribe(code, reason)
+			return
+		}
+		sub.Conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		if apiErr != nil {
+			sub.reply(errorSocket("", apiErr))
+			continue
+		}
+		sub.reply(handleSocketRequest(sub, request))
+	}
+}
+
+// reply queues a message for this socket only.
+func (sub *SocketSub) reply(message interface{}) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	if !socketSubs[sub.GameID][sub] {
+		return
+	}
+	select {
+	case sub.send <- message:
+	default:
+		sub.unsubscribeLocked(websocket.CloseTryAgainLater, "slow consumer")
+	}
+}
+
+// closeStatus picks the close frame to send after a failed read.
+func closeStatus(err error) (int, string) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return websocket.CloseTryAgainLater, "heartbeat timeout"
+	}
+	if errors.Is(err, websocket.ErrReadLimit) {
+		return websocket.CloseMessageTooBig, "message too big"
+	}
+	return websocket.CloseNormalClosure, ""
+}
+
+func (sub *SocketSub) getAudience() string {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	return sub.audience
+}
+
+func (sub *SocketSub) setAudience(audience string) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	sub.audience = audience
+	if socketSubs[sub.GameID][sub] {
+		presenceChanged[sub.GameID] = time.Now()
+	}
+}
+
+func (sub *SocketSub) queuedEvents() bool {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	return sub.hasQueuedEvents
+}
+
+// skipEventsThrough stops queued events the client already has from being
+// written after a replay or snapshot.
+func (sub *SocketSub) skipEventsThrough(sequence int) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	sub.skipThrough = sequence
+}
+
+func (sub *SocketSub) stale(event EventEnvelope) bool {
+	if event.Type == eventSnapshot {
+		return false
+	}
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	return event.Sequence <= sub.skipThrough
+}
+
+// publishSocket queues an event for every socket subscribed to a game, as
+// each socket's audience should see it. Sockets whose queue is full are
+// disconnected so they can reconnect and catch up instead of holding up
+// everyone else.
+func publishSocket(game Game, event EventEnvelope) {
+	gameID := game.GameID
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	for sub := range socketSubs[gameID] {
+		view, ok := viewEvent(game, sub.audience, event)
+		if !ok {
+			continue
+		}
+		if sub.compact {
+			view = compactEvent(view)
+		}
+		select {
+		case sub.send <- view:
+			sub.hasQueuedEvents = true
+		default:
+			fmt.Println("Dropping slow websocket on " + gameID.String())
+			sub.unsubscribeLocked(websocket.CloseTryAgainLater, "slow consumer")
+		}
+	}
+}
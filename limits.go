@@ -0,0 +1,176 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/handlers"
+	uuid "github.com/satori/go.uuid"
+)
+
+// requests turned away, by reason: websockets and event streams over a
+// limit or from a disallowed origin, and cross-origin preflights
+var rejections = expvar.NewMap("rejections")
+
+// open websockets, event streams and grpc watches, counted when they are
+// reserved; guarded by socketSubsLock
+var connectionsPerIP = map[string]int{}
+var connectionsPerGame = map[uuid.UUID]int{}
+
+var errTooManyConnections = newAPIError(http.StatusTooManyRequests, "too_many_connections", "Too many connections from your address.")
+var errGameFull = newAPIError(http.StatusTooManyRequests, "game_full", "Too many connections to this game.")
+
+// originAllowed reports whether a page from origin may call the api.
+// Requests without an origin don't come from a browser page.
+func originAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS lets pages from allowed origins call the api. The counter goes
+// outside, since the cors middleware answers preflights itself.
+func withCORS(handler http.Handler) http.Handler {
+	return countCORSRejections(handlers.CORS(handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}), handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS", "PATCH"}), handlers.AllowedOriginValidator(originAllowed))(handler))
+}
+
+// countCORSRejections counts the preflights the cors middleware is about to
+// turn away, since it doesn't say who it turned away. Other requests from a
+// disallowed origin, including same origin ones, are still served.
+func countCORSRejections(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if req.Method == http.MethodOptions && !originAllowed(origin) {
+			rejections.Add("cors", 1)
+			fmt.Println("Rejected cross-origin preflight from " + origin)
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+// checkSocketOrigin lets a page open a websocket if it is served by us or
+// from an allowed origin.
+func checkSocketOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if originAllowed(origin) {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(parsed.Host, req.Host) {
+		return true
+	}
+	rejectConnection(clientIP(req), "origin", "origin "+origin+" not allowed")
+	return false
+}
+
+// clientIP is the address connection limits are counted against. That is
+// the address the request came from, unless that is a trusted proxy; then
+// it is the nearest address in X-Forwarded-For that isn't one, since a
+// client can put anything at the start of the header.
+func clientIP(req *http.Request) string {
+	ip := clientHost(req.RemoteAddr)
+	if !proxyTrusted(ip) {
+		return ip
+	}
+	forwarded := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := clientHost(strings.TrimSpace(forwarded[i]))
+		if hop == "" {
+			break
+		}
+		ip = hop
+		if !proxyTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// proxyTrusted reports whether ip is one of config.TrustedProxies.
+func proxyTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range config.TrustedProxies {
+		_, network, err := net.ParseCIDR(proxy)
+		if err == nil && network.Contains(parsed) || parsed.Equal(net.ParseIP(proxy)) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientHost is clientIP for an address without a request, like a grpc peer.
+func clientHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err == nil {
+		return host
+	}
+	return addr
+}
+
+func rejectConnection(ip string, reason string, detail string) {
+	rejections.Add(reason, 1)
+	fmt.Println("Rejected connection from " + ip + ": " + detail)
+}
+
+// reserveConnection counts a websocket, event stream or grpc watch against
+// the per address and per game limits before it starts. Websockets release
+// the reservation when the subscription ends, or with releaseConnection if
+// the upgrade fails; streams release it when they end.
+func reserveConnection(gameID uuid.UUID, ip string) *APIError {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	if connectionsPerIP[ip] >= config.Socket.MaxConnectionsPerIP {
+		rejectConnection(ip, "ip_limit", "too many connections from address")
+		return errTooManyConnections
+	}
+	if connectionsPerGame[gameID] >= config.Socket.MaxConnectionsPerGame {
+		rejectConnection(ip, "game_limit", "too many connections to "+gameID.String())
+		return errGameFull
+	}
+	connectionsPerIP[ip]++
+	connectionsPerGame[gameID]++
+	return nil
+}
+
+func releaseConnection(gameID uuid.UUID, ip string) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	releaseConnectionLocked(gameID, ip)
+}
+
+func releaseConnectionLocked(gameID uuid.UUID, ip string) {
+	connectionsPerIP[ip]--
+	if connectionsPerIP[ip] <= 0 {
+		delete(connectionsPerIP, ip)
+	}
+	connectionsPerGame[gameID]--
+	if connectionsPerGame[gameID] <= 0 {
+		delete(connectionsPerGame, gameID)
+	}
+}
+
+// RejectionsHandler serves the rejection counters, and nothing else that
+// expvar knows about.
+func RejectionsHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+		counts := map[string]int64{}
+		rejections.Do(func(kv expvar.KeyValue) {
+			counts[kv.Key] = kv.Value.(*expvar.Int).Value()
+		})
+		writeJSON(res, req, map[string]map[string]int64{"rejections": counts})
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"forged header without a proxy", "203.0.113.5:4000", "198.51.100.1", "203.0.113.5"},
+		{"through a proxy", "10.0.0.1:4000", "198.51.100.1", "198.51.100.1"},
+		{"forged header through a proxy", "10.0.0.1:4000", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"through two proxies", "10.0.0.1:4000", "198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"proxy without a header", "10.0.0.1:4000", "", "10.0.0.1"},
+		{"ipv6 through a proxy", "10.0.0.1:4000", "2001:db8::1", "2001:db8::1"},
+	}
+
+	trusted := config.TrustedProxies
+	config.TrustedProxies = []string{"10.0.0.1", "10.0.0.0/30"}
+	defer func() { config.TrustedProxies = trusted }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/socket/game", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", test.forwarded)
+			}
+			if got := clientIP(req); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCountCORSRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		counted bool
+	}{
+		{"no origin", "POST", "", false},
+		{"same origin post", "POST", "http://example.com", false},
+		{"disallowed preflight", "OPTIONS", "https://evil.example", true},
+		{"allowed preflight", "OPTIONS", "https://ok.example", false},
+	}
+
+	origins := config.AllowedOrigins
+	config.AllowedOrigins = []string{"https://ok.example"}
+	defer func() { config.AllowedOrigins = origins }()
+	handler := withCORS(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := rejectionCount("cors")
+			req := httptest.NewRequest(test.method, "/game", nil)
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			if test.method == "OPTIONS" {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if counted := rejectionCount("cors") > before; counted != test.counted {
+				t.Errorf("got counted %t, want %t", counted, test.counted)
+			}
+		})
+	}
+}
+
+func rejectionCount(reason string) int64 {
+	count := int64(0)
+	if value := rejections.Get(reason); value != nil {
+		count = value.(*expvar.Int).Value()
+	}
+	return count
+}
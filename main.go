@@ -8,28 +8,26 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 
+	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 
-	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
-var config = readConfig()
-var db = getDB(config)
-var socketSubs = []SocketSub{}
-
-// SocketSub is a subscription to a socket.
-type SocketSub struct {
-	GameID uuid.UUID       `json:"gameID"`
-	Conn   *websocket.Conn `json:"-"`
-}
+// set up by main, or by TestMain for the tests
+var config Config
+var db *gorm.DB
 
 // JoinRequest is a request to join a game.
 type JoinRequest struct {
@@ -87,7 +85,25 @@ func createBoard() [8][8]int {
 }
 
 func main() {
+	config = readConfig()
+	db = getDB(config)
+	broadcaster = newBroadcaster(config.Broadcast)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			exportCommand(os.Args[2:])
+			return
+		case "import":
+			importCommand(os.Args[2:])
+			return
+		}
+	}
+
 	fmt.Println("Starting backend.")
+	go retentionJob()
+	go broadcaster.Run()
+	go grpcAPI()
 	api()
 }
 
@@ -95,8 +111,9 @@ func readConfig() Config {
 	if fileExists("config.json") {
 		bytes, err := ioutil.ReadFile("config.json")
 		check(err)
-		config := Config{}
-		json.Unmarshal(bytes, &config)
+		config := defaultConfig
+		check(json.Unmarshal(bytes, &config))
+		check(config.validate())
 		return config
 	}
 	jsonBytes, parseErr := json.MarshalIndent(defaultConfig, "", "   ")
@@ -107,6 +124,61 @@ func readConfig() Config {
 	return defaultConfig
 }
 
+// validate rejects settings the server can't run with, such as zero
+// intervals that would make a ticker panic.
+func (config Config) validate() error {
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{"port", config.Port},
+		{"gameIdleTimeout", config.GameIdleTimeout},
+		{"socket.pingIntervalSeconds", config.Socket.PingIntervalSeconds},
+		{"socket.pongTimeoutSeconds", config.Socket.PongTimeoutSeconds},
+		{"socket.writeTimeoutSeconds", config.Socket.WriteTimeoutSeconds},
+		{"socket.queueSize", config.Socket.QueueSize},
+		{"socket.maxConnectionsPerIP", config.Socket.MaxConnectionsPerIP},
+		{"socket.maxConnectionsPerGame", config.Socket.MaxConnectionsPerGame},
+		{"broadcast.pollMillis", config.Broadcast.PollMillis},
+		{"broadcast.lookbackMillis", config.Broadcast.LookbackMillis},
+	}
+	for _, setting := range positive {
+		if setting.value <= 0 {
+			return fmt.Errorf("config %s must be greater than 0, got %d", setting.name, setting.value)
+		}
+	}
+
+	notNegative := []struct {
+		name  string
+		value int
+	}{
+		{"grpcPort", config.GrpcPort},
+		{"socket.maxReplayEvents", config.Socket.MaxReplayEvents},
+		{"retention.intervalMinutes", config.Retention.IntervalMinutes},
+		{"retention.unjoinedGameHours", config.Retention.UnjoinedGameHours},
+		{"retention.archiveFinishedDays", config.Retention.ArchiveFinishedDays},
+	}
+	for _, setting := range notNegative {
+		if setting.value < 0 {
+			return fmt.Errorf("config %s can not be negative, got %d", setting.name, setting.value)
+		}
+	}
+
+	if config.Socket.PongTimeoutSeconds <= config.Socket.PingIntervalSeconds {
+		return fmt.Errorf("config socket.pongTimeoutSeconds must be longer than socket.pingIntervalSeconds")
+	}
+	if config.Broadcast.Backplane != backplaneLocal && config.Broadcast.Backplane != backplaneDatabase {
+		return fmt.Errorf("config broadcast.backplane must be %s or %s, got %q", backplaneLocal, backplaneDatabase, config.Broadcast.Backplane)
+	}
+	for _, proxy := range config.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		if err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("config trustedProxies must be addresses or cidr ranges, got %q", proxy)
+		}
+	}
+	return nil
+}
+
 // GetIP from http request
 func GetIP(r *http.Request) string {
 	forwarded := r.Header.Get("X-FORWARDED-FOR")
@@ -124,34 +196,48 @@ func SocketHandler() http.Handler {
 		vars := mux.Vars(req)
 		id := vars["id"]
 
-		gameID, err := uuid.FromString(id)
-		if err != nil {
-			fmt.Println("Invalid gameID.")
+		gameID, apiErr := resolveGameID(id)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		if db.First(&Game{}, "game_id = ?", gameID).RecordNotFound() {
+			writeError(res, req, errGameNotFound)
 			return
 		}
 
 		var upgrader = websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			Subprotocols:    []string{socketProtocolJSON, socketProtocolMsgpack},
 		}
 
-		upgrader.CheckOrigin = func(req *http.Request) bool { return true }
+		upgrader.CheckOrigin = checkSocketOrigin
+
+		ip := clientIP(req)
+		apiErr = reserveConnection(gameID, ip)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
 
 		conn, err := upgrader.Upgrade(res, req, nil)
 
 		if err != nil {
+			// the upgrader has already written an error response
 			fmt.Println(err)
-			res.Write([]byte("the client is not using the websocket protocol: 'upgrade' token not found in 'Connection' header"))
+			releaseConnection(gameID, ip)
 			return
 		}
 
 		fmt.Println("Incoming websocket connection.")
 
-		socketSub := SocketSub{
-			GameID: gameID,
-			Conn:   conn,
-		}
-		socketSubs = append(socketSubs, socketSub)
+		compact := req.URL.Query().Get("compact") == "true"
+		sub := subscribeSocket(gameID, ip, conn, compact)
+		go withGame(gameID, updatePresence)
+		sub.reply(SocketChallenge{Type: "challenge", Challenge: sub.challenge})
+		go sub.writePump()
+		go sub.readPump()
 
 		fmt.Println("Added subscription to list.")
 	})
@@ -160,15 +246,21 @@ func SocketHandler() http.Handler {
 func api() {
 	router := mux.NewRouter()
 	router.Handle("/game", GamePostHandler()).Methods("POST")
+	router.Handle("/game/nonce", GameNonceHandler()).Methods("POST")
 	router.Handle("/game", GamePatchHandler()).Methods("PATCH")
+	router.Handle("/games", GameListHandler()).Methods("GET")
 	router.Handle("/game/{id}", GameGetHandler()).Methods("GET")
+	router.Handle("/game/{id}/ply/{n}", GamePlyHandler()).Methods("GET")
+	router.Handle("/game/{id}/events", GameEventsHandler()).Methods("GET")
+	router.Handle("/game/{id}/validate", GameValidateHandler()).Methods("POST")
 	router.Handle("/join/{id}", JoinPostHandler()).Methods("POST")
 	router.Handle("/socket/{id}", SocketHandler()).Methods("GET")
+	router.Handle("/debug/vars", RejectionsHandler()).Methods("GET")
 
 	http.Handle("/", router) // enable the router
 	port := ":" + strconv.Itoa(config.Port)
 	fmt.Println("\nListening on port " + port)
-	log.Fatal(http.ListenAndServe(port, handlers.CORS(handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}), handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS", "PATCH"}), handlers.AllowedOrigins([]string{"*"}))(router)))
+	log.Fatal(http.ListenAndServe(port, withCORS(router)))
 }
 
 // GamePostResponse is a response to the /game endpoint.
@@ -179,24 +271,68 @@ type GamePostResponse struct {
 
 // GameGetResponse is a response to the /game endpoint.
 type GameGetResponse struct {
-	GameID uuid.UUID   `json:"gameID"`
-	State  [][8][8]int `json:"state"`
+	GameID   uuid.UUID   `json:"gameID"`
+	Code     string      `json:"code"`
+	JoinLink string      `json:"joinLink,omitempty"`
+	Result   string      `json:"result,omitempty"`
+	Presence *Presence   `json:"presence,omitempty"`
+	LastPly  int         `json:"lastPly"`
+	State    [][8][8]int `json:"state,omitempty"`
+	Moves    []MoveInfo  `json:"moves,omitempty"`
 }
 
-func storeBoardState(gameID uuid.UUID, state [8][8]int, moveAuthor string) {
-	db.Create(&BoardState{
-		GameID:     gameID,
-		State:      serializeBoard(state),
-		MoveAuthor: moveAuthor,
-	})
+// MoveInfo describes a single ply of a game.
+type MoveInfo struct {
+	Ply        int    `json:"ply"`
+	MoveAuthor string `json:"moveAuthor"`
+	PieceMoved int    `json:"pieceMoved"`
+	PieceTaken int    `json:"pieceTaken"`
+	StartPos   string `json:"startPos"`
+	EndPos     string `json:"endPos"`
+	Check      bool   `json:"check"`
+	CheckMate  bool   `json:"checkMate"`
+}
+
+func moveInfo(ply int, boardState BoardState) MoveInfo {
+	return MoveInfo{
+		Ply:        ply,
+		MoveAuthor: boardState.MoveAuthor,
+		PieceMoved: boardState.PieceMoved,
+		PieceTaken: boardState.PieceTaken,
+		StartPos:   boardState.StartPosition,
+		EndPos:     boardState.EndPosition,
+		Check:      boardState.Check,
+		CheckMate:  boardState.CheckMate,
+	}
 }
 
-// GameStatePush is a websocket notification of a new game state.
-type GameStatePush struct {
-	GameID     uuid.UUID  `json:"gameID"`
-	Board      [8][8]int  `json:"board"`
-	Type       string     `json:"type"`
-	BoardState BoardState `json:"boardState"`
+// broadcast numbers an event, stores it and sends it to everyone watching
+// the game. It must run on the game's actor.
+func broadcast(actor *gameActor, eventType string, payload interface{}) {
+	event := EventEnvelope{
+		Version:   eventEnvelopeVersion,
+		Type:      eventType,
+		GameID:    actor.game.GameID,
+		Sequence:  actor.sequence + 1,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+	for attempt := 0; ; attempt++ {
+		err := storeEvent(event)
+		if err == nil {
+			break
+		}
+		if attempt == maxConflicts {
+			fmt.Println("Could not store " + eventType + " event for " + actor.game.GameID.String() + ": " + err.Error())
+			break
+		}
+		// another instance numbered an event for the game first; ours
+		// follows it, and the actor reloads what else changed afterwards
+		actor.stale = true
+		event.Sequence = lastSequence(actor.game.GameID) + 1
+	}
+	actor.sequence = event.Sequence
+	broadcaster.Publish(actor.game, event)
 }
 
 func finishEnPassant(boardState [8][8]int, moveAuthor string, endPos [2]int) [8][8]int {
@@ -214,101 +350,265 @@ func GamePatchHandler() http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		fmt.Println(req.Method, req.URL, GetIP(req))
 
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			panic(err)
-		}
-
 		var jsonBody ReceivedBoardState
-		json.Unmarshal(body, &jsonBody)
-
-		game := Game{}
-		db.First(&game, "game_id = ?", jsonBody.GameID)
-
-		lastMove := BoardState{}
-		db.Last(&lastMove, "game_id = ?", jsonBody.GameID)
-		sig, err := hex.DecodeString(jsonBody.Signed)
-		if err != nil {
-			fmt.Println("signature is not valid hex string.")
+		apiErr := readBody(req, &jsonBody)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
 			return
 		}
-		verified := false
-		if lastMove.MoveAuthor == "WHITE" {
-			if len(game.BlackPlayer) == 0 {
-				fmt.Println("There is no black player!")
-				return
-			}
-			verified = ed25519.Verify(game.BlackPlayer, serializeBoard(jsonBody.State), sig)
-		}
 
-		if lastMove.MoveAuthor == "BLACK" {
-			if len(game.WhitePlayer) == 0 {
-				fmt.Println("There is no white player!")
-				return
-			}
-			verified = ed25519.Verify(game.WhitePlayer, serializeBoard(jsonBody.State), sig)
+		var newState BoardState
+		failure := withGame(jsonBody.GameID, func(actor *gameActor) {
+			newState, apiErr = makeMove(actor, jsonBody)
+		})
+		if failure != nil {
+			writeError(res, req, failure)
+			return
 		}
-
-		if !verified {
-			fmt.Println("Invalid signature for move.")
+		if apiErr != nil {
+			writeError(res, req, apiErr)
 			return
 		}
+		writeJSON(res, req, newState)
+	})
+}
 
-		var newMoveAuthor string
-		if lastMove.MoveAuthor == "BLACK" {
-			newMoveAuthor = "WHITE"
-		}
-		if lastMove.MoveAuthor == "WHITE" {
-			newMoveAuthor = "BLACK"
+// checkInPlay refuses moves in a finished game, finishing it first if the
+// side to move has run out of time.
+func checkInPlay(actor *gameActor) *APIError {
+	actor.checkFlag()
+	if actor.game.ResultReason == "timeout" {
+		return errTimeOut
+	}
+	if actor.game.Result != "" {
+		return errGameOver
+	}
+	return nil
+}
+
+func makeMove(actor *gameActor, jsonBody ReceivedBoardState) (BoardState, *APIError) {
+	game := actor.game
+	lastMove := actor.history.lastMove
+
+	apiErr := checkInPlay(actor)
+	if apiErr != nil {
+		return BoardState{}, apiErr
+	}
+	newMoveAuthor := nextMoveAuthor(lastMove)
+
+	sig, err := hex.DecodeString(jsonBody.Signed)
+	if err != nil {
+		return BoardState{}, newAPIError(http.StatusBadRequest, "invalid_signature_encoding", "Signature is not valid hex string.")
+	}
+
+	playerKey := sideKey(game, newMoveAuthor)
+	if len(playerKey) == 0 {
+		return BoardState{}, newAPIError(http.StatusConflict, "player_missing", "There is no "+strings.ToLower(newMoveAuthor)+" player.")
+	}
+
+	if !ed25519.Verify(playerKey, serializeBoard(jsonBody.State), sig) {
+		otherKey := sideKey(game, lastMove.MoveAuthor)
+		if len(otherKey) != 0 && ed25519.Verify(otherKey, serializeBoard(jsonBody.State), sig) {
+			return BoardState{}, illegalMove(ViolationNotYourTurn)
 		}
-		valid, pieceMoved, pieceTaken, startPos, endPos, castleType, enPassant, check, checkMate := parseMove(deserializeBoard(lastMove.State), jsonBody.State, newMoveAuthor, game.GameID)
+		return BoardState{}, newAPIError(http.StatusUnauthorized, "invalid_signature", "Invalid signature for move.")
+	}
+
+	newState, result := playMove(game.GameID, lastMove, jsonBody.State, actor.history)
+	if !result.Valid {
+		return BoardState{}, illegalMove(result.Violation)
+	}
+	oldBoard := deserializeBoard(lastMove.State)
+	newBoard := deserializeBoard(newState.State)
+	newState.Signature = jsonBody.Signed
+	newState.Ply = actor.history.lastPly + 1
+
+	// moving instead of accepting turns down a draw offer
+	if game.DrawOffer != "" && game.DrawOffer != newState.MoveAuthor {
+		actor.game.DrawOffer = ""
+	}
+	if !storeMove(actor, &newState) {
+		actor.conflict = true
+		return BoardState{}, errConflict
+	}
+	actor.history.record(newState)
+	actor.clock.record(newState.MoveAuthor, newState.CreatedAt)
+
+	broadcast(actor, eventMoveMade, MoveMadePayload{
+		Ply:          actor.history.lastPly,
+		Board:        newBoard,
+		BoardState:   newState,
+		SquareDiffs:  getSquareDiffs(oldBoard, newBoard),
+		UCI:          toUCI(newState, newBoard),
+		PositionHash: positionHash(newBoard),
+	})
+	if actor.clock.timed {
+		broadcast(actor, eventClockUpdate, actor.clock.payload(nextMoveAuthor(newState)))
+	}
+	if newState.CheckMate {
+		finishGame(actor, newState.MoveAuthor, winner(newState.MoveAuthor), "checkmate")
+	} else if newState.Check {
+		broadcast(actor, eventCheck, CheckPayload{
+			Ply:  actor.history.lastPly,
+			Side: nextMoveAuthor(newState),
+		})
+	}
+
+	return newState, nil
+}
+
+// ValidateRequest is an unsigned move to check against the rules.
+type ValidateRequest struct {
+	State [8][8]int `json:"state"`
+}
 
-		if lastMove.Check && check {
-			fmt.Println("Move does not resolve check.")
+// ValidateResponse is the outcome of a dry-run move.
+type ValidateResponse struct {
+	Valid     bool      `json:"valid"`
+	Violation Violation `json:"violation,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Board     [8][8]int `json:"board"`
+	SAN       string    `json:"san,omitempty"`
+	Check     bool      `json:"check"`
+	CheckMate bool      `json:"checkMate"`
+}
+
+// GameValidateHandler checks a move without requiring a signature or storing it.
+func GameValidateHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+
+		vars := mux.Vars(req)
+		gameID, apiErr := resolveGameID(vars["id"])
+		if apiErr != nil {
+			writeError(res, req, apiErr)
 			return
 		}
 
-		if castleType != "" {
-			jsonBody.State = finishCastle(jsonBody.State, pieceColor(pieceMoved), castleType)
+		var jsonBody ValidateRequest
+		apiErr = readBody(req, &jsonBody)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
 		}
 
-		if enPassant {
-			// modify state
-			jsonBody.State = finishEnPassant(jsonBody.State, pieceColor(pieceMoved), endPos)
+		var response ValidateResponse
+		failure := withGame(gameID, func(actor *gameActor) {
+			response, apiErr = validateMove(actor, jsonBody.State)
+		})
+		if failure != nil {
+			writeError(res, req, failure)
+			return
 		}
-
-		if valid {
-			newState := BoardState{
-				GameID:        jsonBody.GameID,
-				State:         serializeBoard(jsonBody.State),
-				MoveAuthor:    newMoveAuthor,
-				PieceMoved:    pieceMoved,
-				PieceTaken:    pieceTaken,
-				StartPosition: posToString(startPos),
-				EndPosition:   posToString(endPos),
-				Check:         check,
-				CheckMate:     checkMate,
-			}
-			db.Create(&newState)
-			broadcastState := GameStatePush{
-				GameID:     jsonBody.GameID,
-				Board:      jsonBody.State,
-				Type:       "move",
-				BoardState: newState,
-			}
-			for _, sub := range socketSubs {
-				if sub.GameID == jsonBody.GameID {
-					// send the new state
-					sub.Conn.WriteJSON(broadcastState)
-				}
-			}
-		} else {
-			fmt.Println("Move is not valid.")
+		if apiErr != nil {
+			writeError(res, req, apiErr)
 			return
 		}
+		writeJSON(res, req, response)
 	})
 }
 
+// validateMove checks a move the way makeMove would without playing it.
+func validateMove(actor *gameActor, submitted [8][8]int) (ValidateResponse, *APIError) {
+	apiErr := checkInPlay(actor)
+	if apiErr != nil {
+		return ValidateResponse{}, apiErr
+	}
+	lastMove := actor.history.lastMove
+	newState, result := playMove(actor.game.GameID, lastMove, submitted, actor.history)
+	if !result.Valid {
+		return ValidateResponse{
+			Violation: result.Violation,
+			Message:   violationMessages[result.Violation],
+			Board:     deserializeBoard(lastMove.State),
+		}, nil
+	}
+
+	board := deserializeBoard(newState.State)
+	return ValidateResponse{
+		Valid:     true,
+		Board:     board,
+		SAN:       toSAN(deserializeBoard(lastMove.State), board, result, actor.history),
+		Check:     result.Check,
+		CheckMate: result.CheckMate,
+	}, nil
+}
+
+func nextMoveAuthor(lastMove BoardState) string {
+	if lastMove.MoveAuthor == "BLACK" {
+		return "WHITE"
+	}
+	if lastMove.MoveAuthor == "WHITE" {
+		return "BLACK"
+	}
+	return ""
+}
+
+func sideKey(game Game, side string) ed25519.PublicKey {
+	if side == "WHITE" {
+		return game.WhitePlayer
+	}
+	if side == "BLACK" {
+		return game.BlackPlayer
+	}
+	return nil
+}
+
+// playMove runs a submitted board through the rules and returns the board
+// state that results from it.
+func playMove(gameID uuid.UUID, lastMove BoardState, submitted [8][8]int, history moveHistory) (BoardState, MoveResult) {
+	newMoveAuthor := nextMoveAuthor(lastMove)
+	state := submitted
+	result := parseMove(deserializeBoard(lastMove.State), state, newMoveAuthor, history)
+
+	if lastMove.Check && result.Check && (result.Valid || result.Violation == ViolationLeavesKingInCheck) {
+		result = result.refuse(ViolationUnresolvedCheck)
+	}
+	if !result.Valid {
+		return BoardState{}, result
+	}
+
+	if result.CastleType != "" {
+		state = finishCastle(state, pieceColor(result.PieceMoved), result.CastleType)
+	}
+
+	if result.EnPassant {
+		// modify state
+		state = finishEnPassant(state, pieceColor(result.PieceMoved), result.EndPos)
+	}
+
+	return BoardState{
+		GameID:        gameID,
+		State:         serializeBoard(state),
+		SignedState:   serializeBoard(submitted),
+		MoveAuthor:    newMoveAuthor,
+		PieceMoved:    result.PieceMoved,
+		PieceTaken:    result.PieceTaken,
+		StartPosition: posToString(result.StartPos),
+		EndPosition:   posToString(result.EndPos),
+		Check:         result.Check,
+		CheckMate:     result.CheckMate,
+	}, result
+}
+
+// MoveRejection is the detail sent back with a refused move.
+type MoveRejection struct {
+	Violation Violation `json:"violation"`
+}
+
+func illegalMove(violation Violation) *APIError {
+	status := http.StatusUnprocessableEntity
+	if violation == ViolationNotYourTurn {
+		status = http.StatusForbidden
+	}
+	return &APIError{
+		Status:  status,
+		Code:    "illegal_move",
+		Message: violationMessages[violation],
+		Details: MoveRejection{Violation: violation},
+	}
+}
+
 func finishCastle(state [8][8]int, moveAuthor string, castleType string) [8][8]int {
 	if moveAuthor == "WHITE" {
 		if castleType == "KING" {
@@ -373,57 +673,100 @@ func getSquareDiffs(oldState [8][8]int, newState [8][8]int) []squareDiff {
 	return squareDiffs
 }
 
+// Violation is the rule a refused move broke.
+type Violation string
+
+// Violations reported by the move validator.
+const (
+	ViolationNone                 Violation = ""
+	ViolationNotYourTurn          Violation = "NOT_YOUR_TURN"
+	ViolationInvalidBoard         Violation = "INVALID_BOARD"
+	ViolationWrongPieceColor      Violation = "WRONG_PIECE_COLOR"
+	ViolationCaptureOwnPiece      Violation = "CAPTURE_OWN_PIECE"
+	ViolationIllegalPieceMove     Violation = "ILLEGAL_PIECE_MOVE"
+	ViolationBlockedPath          Violation = "BLOCKED_PATH"
+	ViolationInvalidPromotion     Violation = "INVALID_PROMOTION"
+	ViolationLeavesKingInCheck    Violation = "LEAVES_KING_IN_CHECK"
+	ViolationUnresolvedCheck      Violation = "UNRESOLVED_CHECK"
+	ViolationInvalidEnPassant     Violation = "INVALID_EN_PASSANT"
+	ViolationCastlePiecesMoved    Violation = "CASTLE_PIECES_MOVED"
+	ViolationCastlingThroughCheck Violation = "CASTLING_THROUGH_CHECK"
+)
+
+var violationMessages = map[Violation]string{
+	ViolationNotYourTurn:          "It is not your turn.",
+	ViolationInvalidBoard:         "Board must differ from the last position by exactly one move.",
+	ViolationWrongPieceColor:      "You did not move your own piece.",
+	ViolationCaptureOwnPiece:      "Can not take your own piece.",
+	ViolationIllegalPieceMove:     "That piece can not move that way.",
+	ViolationBlockedPath:          "Another piece is in the way.",
+	ViolationInvalidPromotion:     "Invalid promotion.",
+	ViolationLeavesKingInCheck:    "Can not move own king into check.",
+	ViolationUnresolvedCheck:      "Move does not resolve check.",
+	ViolationInvalidEnPassant:     "En passant is not possible here.",
+	ViolationCastlePiecesMoved:    "The king or rook has already moved or been taken.",
+	ViolationCastlingThroughCheck: "Can not castle out of or through check.",
+}
+
+// MoveResult is the outcome of checking a move against the rules.
+type MoveResult struct {
+	Valid      bool      `json:"valid"`
+	Violation  Violation `json:"violation,omitempty"`
+	PieceMoved int       `json:"pieceMoved"`
+	PieceTaken int       `json:"pieceTaken"`
+	StartPos   [2]int    `json:"startPos"`
+	EndPos     [2]int    `json:"endPos"`
+	CastleType string    `json:"castleType,omitempty"`
+	EnPassant  bool      `json:"enPassant"`
+	Check      bool      `json:"check"`
+	CheckMate  bool      `json:"checkMate"`
+}
+
+func (result MoveResult) allow() MoveResult {
+	result.Valid = true
+	result.Violation = ViolationNone
+	return result
+}
+
+func (result MoveResult) refuse(violation Violation) MoveResult {
+	result.Valid = false
+	result.Violation = violation
+	return result
+}
+
+func (result MoveResult) allowIf(legal bool, violation Violation) MoveResult {
+	if legal {
+		return result.allow()
+	}
+	return result.refuse(violation)
+}
+
 /*
 - IF any square in between the king and rook is attacked, a castle is not legal
 - Detect checkmate
 */
-func parseMove(oldState [8][8]int, newState [8][8]int, moveAuthor string, gameID uuid.UUID) (bool, int, int, [2]int, [2]int, string, bool, bool, bool) {
+func parseMove(oldState [8][8]int, newState [8][8]int, moveAuthor string, history moveHistory) MoveResult {
 	squareDiffs := getSquareDiffs(oldState, newState)
-	startPos := [2]int{}
-	endPos := [2]int{}
-	castleType := ""
-	enPassant := false
-	check := false
-	checkMate := false
+	result := MoveResult{}
 
-	var pieceMoved int
-	var pieceTaken int
-	if len(squareDiffs) > 2 {
-		fmt.Println("Expected square diff of length<=2, but received length " + strconv.Itoa(len(squareDiffs)))
-		return false, pieceMoved, pieceTaken, startPos, endPos, castleType, enPassant, check, checkMate
+	if len(squareDiffs) != 2 {
+		fmt.Println("Expected square diff of length 2, but received length " + strconv.Itoa(len(squareDiffs)))
+		return result.refuse(ViolationInvalidBoard)
 	}
-	if len(squareDiffs) == 2 {
-		for _, diff := range squareDiffs {
-			if diff.Added == empty {
-				pieceMoved = diff.Removed
-			}
-		}
-		if squareDiffs[0].Added == empty {
-			pieceMoved = squareDiffs[0].Removed
+	for _, diff := range squareDiffs {
+		if diff.Added == empty {
+			result.PieceMoved = diff.Removed
 		}
 	}
-	if len(squareDiffs) <= 1 {
-		fmt.Println("Expected square diff of length<=2, but received length " + strconv.Itoa(len(squareDiffs)))
-		return false, pieceMoved, pieceTaken, startPos, endPos, castleType, enPassant, check, checkMate
+	if squareDiffs[0].Added == empty {
+		result.PieceMoved = squareDiffs[0].Removed
 	}
 
-	if pieceColor(pieceMoved) != moveAuthor {
-		fmt.Println("User did not move their own piece.")
-		return false, pieceMoved, pieceTaken, startPos, endPos, castleType, enPassant, check, checkMate
+	if pieceColor(result.PieceMoved) != moveAuthor {
+		return result.refuse(ViolationWrongPieceColor)
 	}
-	legal, pieceTaken, sPos, ePos, cType, enPas, chk, chkMate := legalMoveForPiece(pieceMoved, squareDiffs, newState, moveAuthor, gameID)
-	startPos = sPos
-	endPos = ePos
-	castleType = cType
-	enPassant = enPas
-	check = chk
-	checkMate = chkMate
 
-	if !legal {
-		fmt.Println("Illegal move for piece " + strconv.Itoa(pieceMoved))
-		return false, pieceMoved, pieceTaken, startPos, endPos, castleType, enPassant, check, checkMate
-	}
-	return true, pieceMoved, pieceTaken, startPos, endPos, castleType, enPassant, check, checkMate
+	return legalMoveForPiece(result.PieceMoved, squareDiffs, newState, moveAuthor, history)
 }
 
 func rowToString(row int) string {
@@ -607,7 +950,7 @@ func squaresBetweenClear(startPos [2]int, endPos [2]int, boardState [8][8]int) b
 	return clear
 }
 
-func legalEnPassant(gameID uuid.UUID, boardState [8][8]int, moveAuthor string, startPos [2]int, endPos [2]int) bool {
+func legalEnPassant(history moveHistory, boardState [8][8]int, moveAuthor string, startPos [2]int, endPos [2]int) bool {
 	if moveAuthor == "WHITE" {
 		// is it starting from the correct row?
 		if startPos[0] != 3 {
@@ -618,9 +961,7 @@ func legalEnPassant(gameID uuid.UUID, boardState [8][8]int, moveAuthor string, s
 			return false
 		}
 		// was the pawn just pushed?
-		lastMove := BoardState{}
-		db.Last(&lastMove, "game_id = ?", gameID)
-		if lastMove.StartPosition != posToString([2]int{endPos[0] - 1, endPos[1]}) {
+		if history.lastMove.StartPosition != posToString([2]int{endPos[0] - 1, endPos[1]}) {
 			return false
 		}
 		return true
@@ -635,9 +976,7 @@ func legalEnPassant(gameID uuid.UUID, boardState [8][8]int, moveAuthor string, s
 			return false
 		}
 		// was the pawn just pushed?
-		lastMove := BoardState{}
-		db.Last(&lastMove, "game_id = ?", gameID)
-		if lastMove.StartPosition != posToString([2]int{endPos[0] + 1, endPos[1]}) {
+		if history.lastMove.StartPosition != posToString([2]int{endPos[0] + 1, endPos[1]}) {
 			return false
 		}
 		return true
@@ -958,7 +1297,7 @@ func squareOpen(boardState [8][8]int, location [2]int, piece int) bool {
 	return false
 }
 
-func legalMoves(location [2]int, boardState [8][8]int, gameID uuid.UUID) [][2]int {
+func legalMoves(location [2]int, boardState [8][8]int, history moveHistory) [][2]int {
 	piece := boardState[location[0]][location[1]]
 	moves := [][2]int{}
 
@@ -969,14 +1308,14 @@ func legalMoves(location [2]int, boardState [8][8]int, gameID uuid.UUID) [][2]in
 				continue
 			}
 			moveLoc := [2]int{location[0] + move[0], location[1] + move[1]}
-			if (locWithinBounds(moveLoc) && squareOpen(boardState, moveLoc, piece)) || legalEnPassant(gameID, boardState, pieceColor(piece), location, moveLoc) {
+			if locWithinBounds(moveLoc) && (squareOpen(boardState, moveLoc, piece) || legalEnPassant(history, boardState, pieceColor(piece), location, moveLoc)) {
 				moves = append(moves, moveLoc)
 			}
 		}
 	case blackPawn:
 		for _, move := range blackPawnMoves {
 			moveLoc := [2]int{location[0] + move[0], location[1] + move[1]}
-			if locWithinBounds(moveLoc) && squareOpen(boardState, moveLoc, piece) || legalEnPassant(gameID, boardState, pieceColor(piece), location, moveLoc) {
+			if locWithinBounds(moveLoc) && (squareOpen(boardState, moveLoc, piece) || legalEnPassant(history, boardState, pieceColor(piece), location, moveLoc)) {
 				moves = append(moves, moveLoc)
 			}
 		}
@@ -1030,7 +1369,7 @@ func legalMoves(location [2]int, boardState [8][8]int, gameID uuid.UUID) [][2]in
 	return moves
 }
 
-func checkMateStatus(boardState [8][8]int, color string, gameID uuid.UUID) bool {
+func checkMateStatus(boardState [8][8]int, color string, history moveHistory) bool {
 	kingSquare := [2]int{}
 	checkMate := true
 	if color == "WHITE" {
@@ -1055,9 +1394,14 @@ func checkMateStatus(boardState [8][8]int, color string, gameID uuid.UUID) bool
 	for i, row := range boardState {
 		for j, piece := range row {
 			if pieceColor(piece) == color {
-				for _, mov := range legalMoves([2]int{i, j}, boardState, gameID) {
+				for _, mov := range legalMoves([2]int{i, j}, boardState, history) {
 					testState := movePiece(boardState, [2]int{i, j}, mov)
-					if !isAttacked(testState, kingSquare, color) {
+					// the king may be the piece getting out of check
+					target := kingSquare
+					if [2]int{i, j} == kingSquare {
+						target = mov
+					}
+					if !isAttacked(testState, target, color) {
 						checkMate = false
 					}
 				}
@@ -1073,37 +1417,33 @@ func movePiece(boardState [8][8]int, startPos [2]int, endPos [2]int) [8][8]int {
 	return boardState
 }
 
-func legalMoveForPiece(piece int, move []squareDiff, boardState [8][8]int, moveAuthor string, gameID uuid.UUID) (bool, int, [2]int, [2]int, string, bool, bool, bool) {
-	startPos := [2]int{}
-	endPos := [2]int{}
-	cType := ""
-	enPassant := false
-	var pieceTaken int
+func legalMoveForPiece(piece int, move []squareDiff, boardState [8][8]int, moveAuthor string, history moveHistory) MoveResult {
+	result := MoveResult{PieceMoved: piece}
 	var pieceAdded int
-	check := false
-	checkMate := false
 	if move[0].Added == empty {
-		startPos = [2]int{move[0].Row, move[0].Column}
-		endPos = [2]int{move[1].Row, move[1].Column}
+		result.StartPos = [2]int{move[0].Row, move[0].Column}
+		result.EndPos = [2]int{move[1].Row, move[1].Column}
 		if move[1].Removed != 88 {
-			pieceTaken = move[1].Removed
+			result.PieceTaken = move[1].Removed
 		}
 		pieceAdded = move[1].Added
 	}
 	if move[1].Added == empty {
-		startPos = [2]int{move[1].Row, move[1].Column}
-		endPos = [2]int{move[0].Row, move[0].Column}
+		result.StartPos = [2]int{move[1].Row, move[1].Column}
+		result.EndPos = [2]int{move[0].Row, move[0].Column}
 
 		if move[0].Removed != empty {
-			pieceTaken = move[0].Removed
+			result.PieceTaken = move[0].Removed
 		}
 		pieceAdded = move[0].Added
 	}
+	startPos := result.StartPos
+	endPos := result.EndPos
+	pieceTaken := result.PieceTaken
 
 	if pieceTaken != empty {
 		if moveAuthor == pieceColor(pieceTaken) {
-			fmt.Println("Can not take your own piece.")
-			return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.refuse(ViolationCaptureOwnPiece)
 		}
 	}
 
@@ -1113,30 +1453,32 @@ func legalMoveForPiece(piece int, move []squareDiff, boardState [8][8]int, moveA
 	if pieceAdded != piece {
 		// only pawns can promote
 		if piece != whitePawn && piece != blackPawn {
-			return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.refuse(ViolationInvalidPromotion)
 		}
 		// did the pawn move start from the second to last row?
-		fmt.Println(startPos[0])
 		if moveAuthor == "WHITE" {
 			if startPos[0] != 1 {
-				return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+				return result.refuse(ViolationInvalidPromotion)
 			}
 		}
 		if moveAuthor == "BLACK" {
 			if startPos[0] != 6 {
-				return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+				return result.refuse(ViolationInvalidPromotion)
 			}
 		}
-		// you can't promote to a king
-		if pieceAdded == whiteKing || pieceAdded == blackKing {
-			return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		// you can't promote to a king, or to the other side's piece
+		if pieceAdded == whiteKing || pieceAdded == blackKing || pieceColor(pieceAdded) != moveAuthor {
+			return result.refuse(ViolationInvalidPromotion)
 		}
 	}
+	// a pawn reaching the last rank has to promote
+	if piece == whitePawn && endPos[0] == 0 && pieceAdded == piece || piece == blackPawn && endPos[0] == 7 && pieceAdded == piece {
+		return result.refuse(ViolationInvalidPromotion)
+	}
 
-	selfCheck := checkStatus(boardState, moveAuthor)
-	if selfCheck {
-		fmt.Println("Can not move own king into check.")
-		return false, pieceTaken, startPos, endPos, cType, enPassant, selfCheck, checkMate
+	if checkStatus(boardState, moveAuthor) {
+		result.Check = true
+		return result.refuse(ViolationLeavesKingInCheck)
 	}
 
 	var otherSide string
@@ -1146,9 +1488,9 @@ func legalMoveForPiece(piece int, move []squareDiff, boardState [8][8]int, moveA
 	if moveAuthor == "BLACK" {
 		otherSide = "WHITE"
 	}
-	check = checkStatus(boardState, otherSide)
-	if check {
-		checkMate = checkMateStatus(boardState, otherSide, gameID)
+	result.Check = checkStatus(boardState, otherSide)
+	if result.Check {
+		result.CheckMate = checkMateStatus(boardState, otherSide, history)
 	}
 
 	switch piece {
@@ -1156,121 +1498,125 @@ func legalMoveForPiece(piece int, move []squareDiff, boardState [8][8]int, moveA
 		if (rowCheck == 1 || rowCheck == 2) && colCheck == 0 && pieceTaken == 0 {
 			if rowCheck == 2 {
 				if startPos[0] == 6 {
-					return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+					return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 				}
-				return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+				return result.refuse(ViolationIllegalPieceMove)
 			}
-			return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 		}
 		if (rowCheck == 1) && (colCheck == 1 || colCheck == -1) && pieceTaken != 0 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if (rowCheck == 1) && (colCheck == 1 || colCheck == -1) && pieceTaken == 0 {
-			enPassant = true
-			return legalEnPassant(gameID, boardState, moveAuthor, startPos, endPos), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			result.EnPassant = true
+			return result.allowIf(legalEnPassant(history, boardState, moveAuthor, startPos, endPos), ViolationInvalidEnPassant)
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	case blackPawn:
 		if (rowCheck == -1 || rowCheck == -2) && colCheck == 0 && pieceTaken == 0 {
 			if rowCheck == -2 {
 				if startPos[0] == 1 {
-					return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+					return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 				}
-				return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+				return result.refuse(ViolationIllegalPieceMove)
 			}
-			return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 		}
 		if (rowCheck == -1) && (colCheck == 1 || colCheck == -1) && pieceTaken != 0 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if (rowCheck == -1) && (colCheck == 1 || colCheck == -1) && pieceTaken == 0 {
-			enPassant = true
-			return legalEnPassant(gameID, boardState, moveAuthor, startPos, endPos), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			result.EnPassant = true
+			return result.allowIf(legalEnPassant(history, boardState, moveAuthor, startPos, endPos), ViolationInvalidEnPassant)
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	case whiteKnight, blackKnight:
 		if rowCheck == 2 && colCheck == -1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 2 && colCheck == 1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 1 && colCheck == 2 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 1 && colCheck == -2 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -1 && colCheck == -2 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -1 && colCheck == 2 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -2 && colCheck == 1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -2 && colCheck == -1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	case whiteBishop, blackBishop:
 		if math.Abs(float64(rowCheck)) == math.Abs(float64(colCheck)) {
-			return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	case whiteRook, blackRook:
 		if rowCheck == 0 || colCheck == 0 {
-			return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	case whiteQueen, blackQueen:
 		if math.Abs(float64(rowCheck)) == math.Abs(float64(colCheck)) || (rowCheck == 0 || colCheck == 0) {
-			return squaresBetweenClear(startPos, endPos, boardState), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allowIf(squaresBetweenClear(startPos, endPos, boardState), ViolationBlockedPath)
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	case whiteKing, blackKing:
 		if rowCheck == 1 && colCheck == 0 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -1 && colCheck == 0 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 0 && colCheck == 1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 0 && colCheck == -1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 1 && colCheck == 1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == 1 && colCheck == -1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -1 && colCheck == 1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
 		}
 		if rowCheck == -1 && colCheck == -1 {
-			return true, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			return result.allow()
+		}
+		if (colCheck == 2 || colCheck == -2) && rowCheck == 0 && pieceTaken != 0 {
+			// castling never captures
+			return result.refuse(ViolationBlockedPath)
 		}
 		if rowCheck == 0 && colCheck == 2 {
-			fmt.Println("Queenside castle detected.")
-			cType = "QUEEN"
-			return isLegalCastle("QUEEN", boardState, moveAuthor, gameID, startPos, endPos), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			result.CastleType = "QUEEN"
+			violation := isLegalCastle("QUEEN", boardState, moveAuthor, history, startPos, endPos)
+			return result.allowIf(violation == ViolationNone, violation)
 		}
 		if rowCheck == 0 && colCheck == -2 {
-			fmt.Println("Kingside castle detected.")
-			cType = "KING"
-			return isLegalCastle("KING", boardState, moveAuthor, gameID, startPos, endPos), pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+			result.CastleType = "KING"
+			violation := isLegalCastle("KING", boardState, moveAuthor, history, startPos, endPos)
+			return result.allowIf(violation == ViolationNone, violation)
 		}
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	default:
-		return false, pieceTaken, startPos, endPos, cType, enPassant, check, checkMate
+		return result.refuse(ViolationIllegalPieceMove)
 	}
 }
 
-func isLegalCastle(direction string, boardState [8][8]int, moveAuthor string, gameID uuid.UUID, startPos [2]int, endPos [2]int) bool {
+func isLegalCastle(direction string, boardState [8][8]int, moveAuthor string, history moveHistory, startPos [2]int, endPos [2]int) Violation {
 	kingPos := ""
 	rookPos := ""
 
@@ -1293,39 +1639,34 @@ func isLegalCastle(direction string, boardState [8][8]int, moveAuthor string, ga
 		}
 	}
 
-	kingMoveList := []BoardState{}
-	rookMoveList := []BoardState{}
-
-	// D8 is starting position of white king
-	db.Where("game_id = ? AND start_position = ?", gameID, kingPos).Find(&kingMoveList)
-	db.Where("game_id = ? AND start_position = ?", gameID, rookPos).Find(&rookMoveList)
-
-	if len(kingMoveList) > 0 || len(rookMoveList) > 0 {
-		return false
+	// a rook taken on its square never moved from it, so look for it too
+	rook := whiteRook
+	if moveAuthor == "BLACK" {
+		rook = blackRook
+	}
+	rookLoc := stringToPos(rookPos)
+	if history.movedFrom[kingPos] || history.movedFrom[rookPos] || boardState[rookLoc[0]][rookLoc[1]] != rook {
+		return ViolationCastlePiecesMoved
 	}
 
-	if direction == "KING" {
-		for i, square := range squaresTowards(startPos, "E", boardState) {
-			if i > 2 {
-				break
-			}
-			if isAttacked(boardState, square, moveAuthor) {
-				return false
-			}
+	step := 1
+	if direction == "QUEEN" {
+		step = -1
+	}
+	// every square between the king and rook must be empty, besides the one
+	// the king has been moved to
+	for col := startPos[1] + step; col != rookLoc[1]; col += step {
+		if col != endPos[1] && boardState[startPos[0]][col] != empty {
+			return ViolationBlockedPath
 		}
 	}
-	if direction == "QUEEN" {
-		for i, square := range squaresTowards(startPos, "W", boardState) {
-			if i > 2 {
-				break
-			}
-			if isAttacked(boardState, square, moveAuthor) {
-				return false
-			}
+	// the king may not start on, pass over or land on an attacked square
+	for col := startPos[1]; col != endPos[1]+step; col += step {
+		if isAttacked(boardState, [2]int{startPos[0], col}, moveAuthor) {
+			return ViolationCastlingThroughCheck
 		}
 	}
-
-	return squaresBetweenClear(startPos, endPos, boardState)
+	return ViolationNone
 }
 
 // GameGetHandler handles the get method on the game endpoint.
@@ -1334,49 +1675,111 @@ func GameGetHandler() http.Handler {
 		fmt.Println(req.Method, req.URL, GetIP(req))
 
 		vars := mux.Vars(req)
-		gameID, err := uuid.FromString(vars["id"])
-		if err != nil {
-			fmt.Println("bad game ID")
+		gameID, apiErr := resolveGameID(vars["id"])
+		if apiErr != nil {
+			writeError(res, req, apiErr)
 			return
 		}
 		game := Game{}
-		db.Where("game_id = ?", gameID).First(&game)
+		if db.Where("game_id = ?", gameID).First(&game).RecordNotFound() {
+			writeError(res, req, errGameNotFound)
+			return
+		}
+		if game.Result == "" && game.InitialSeconds > 0 {
+			// loading the actor ends the game if a flag has fallen
+			withGame(gameID, func(actor *gameActor) {
+				game = actor.game
+			})
+		}
 
-		var state [][8][8]int
-		boardStates := []BoardState{}
-		db.Where("game_id = ?", game.GameID).Find(&boardStates)
+		params := req.URL.Query()
+		view := params.Get("view")
+		if view == "" {
+			view = "boards"
+		}
+		if view != "boards" && view != "moves" && view != "latest" {
+			writeError(res, req, newAPIError(http.StatusBadRequest, "invalid_view", "View must be boards, moves or latest."))
+			return
+		}
+
+		total := 0
+		db.Model(&BoardState{}).Where("game_id = ?", game.GameID).Count(&total)
+
+		since, apiErr := intParam(params.Get("since"), 0)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		limit, apiErr := intParam(params.Get("limit"), total)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		if view == "latest" {
+			since = total - 1
+			limit = 1
+		}
 
-		for _, row := range boardStates {
-			state = append(state, deserializeBoard(row.State))
+		latest := BoardState{}
+		db.Where("game_id = ?", game.GameID).Last(&latest)
+		// a resignation or draw changes the game without adding a ply
+		version := strconv.Itoa(total - 1)
+		lastModified := latest.CreatedAt
+		if game.Result != "" {
+			version += "-" + game.Result
+			if game.UpdatedAt.After(lastModified) {
+				lastModified = game.UpdatedAt
+			}
+		}
+		// who is connected is only reported while the game is being played,
+		// so finished games can still be cached forever
+		finished := game.Result != "" || latest.CheckMate
+		var presence *Presence
+		if !finished {
+			current, changed := broadcaster.Presence(game.GameID)
+			presence = &current
+			version += fmt.Sprintf("-%t-%t-%d", current.WhiteConnected, current.BlackConnected, current.Watching)
+			if changed.After(lastModified) {
+				lastModified = changed
+			}
+		}
+		if notModified(res, req, gameETag(req, version), lastModified, finished) {
+			return
 		}
 
+		boardStates := []BoardState{}
+		db.Where("game_id = ?", game.GameID).Order("id").Offset(since).Limit(limit).Find(&boardStates)
+
 		response := GameGetResponse{
-			GameID: game.GameID,
-			State:  state,
+			GameID:   game.GameID,
+			Code:     game.Code,
+			JoinLink: joinLink(game),
+			Result:   game.Result,
+			Presence: presence,
+			LastPly:  total - 1,
+		}
+		for i, row := range boardStates {
+			if view != "moves" {
+				response.State = append(response.State, deserializeBoard(row.State))
+			}
+			if view != "boards" {
+				response.Moves = append(response.Moves, moveInfo(since+i, row))
+			}
 		}
 
-		byteRes, err := json.Marshal(response)
-		check(err)
-		res.Write(byteRes)
+		writeJSON(res, req, response)
 	})
 }
 
-// GamePostHandler handles the game endpoint.
-func GamePostHandler() http.Handler {
-	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		fmt.Println(req.Method, req.URL, GetIP(req))
-
-		game := Game{
-			GameID: uuid.NewV4(),
-		}
-		db.Create(&game)
-		storeBoardState(game.GameID, createBoard(), "BLACK")
-		// res.Header().Set("Content-Type", "application/x-msgpack")
-		res.Header().Set("Content-Type", "application/json")
-		byteRes, err := json.Marshal(game)
-		check(err)
-		res.Write(byteRes)
-	})
+func intParam(param string, fallback int) (int, *APIError) {
+	if param == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(param)
+	if err != nil || value < 0 {
+		return 0, newAPIError(http.StatusBadRequest, "invalid_parameter", param+" is not a positive number.")
+	}
+	return value, nil
 }
 
 // JoinPostHandler handles the post endpoint.
@@ -1385,66 +1788,80 @@ func JoinPostHandler() http.Handler {
 		fmt.Println(req.Method, req.URL, GetIP(req))
 
 		vars := mux.Vars(req)
-		gameID, err := uuid.FromString(vars["id"])
-		if err != nil {
-			fmt.Println("bad game ID")
+		gameID, apiErr := resolveGameID(vars["id"])
+		if apiErr != nil {
+			writeError(res, req, apiErr)
 			return
 		}
 
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			panic(err)
-		}
-
 		var jsonBody JoinRequest
-		json.Unmarshal(body, &jsonBody)
-
-		game := Game{}
-		db.First(&game, "game_id = ?", gameID)
+		apiErr = readBody(req, &jsonBody)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
 
-		var requestedSide ed25519.PublicKey
-		if jsonBody.Side == "WHITE" {
-			requestedSide = game.WhitePlayer
+		var game Game
+		failure := withGame(gameID, func(actor *gameActor) {
+			game, apiErr = joinGame(actor, jsonBody)
+		})
+		if failure != nil {
+			writeError(res, req, failure)
+			return
 		}
-		if jsonBody.Side == "BLACK" {
-			requestedSide = game.BlackPlayer
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
 		}
+		writeJSON(res, req, game)
+	})
+}
 
-		if len(requestedSide) == 0 {
-			fmt.Println("Nobody is playing " + jsonBody.Side + " currently, checking signature.")
-			sig, err := hex.DecodeString(jsonBody.Signed)
-			if err != nil {
-				fmt.Println("Signature is not valid hex string.")
-				return
-			}
+func joinGame(actor *gameActor, jsonBody JoinRequest) (Game, *APIError) {
+	game := &actor.game
+	gameID := game.GameID
 
-			pubKey, err := hex.DecodeString(jsonBody.PubKey)
-			if err != nil {
-				fmt.Println("Public key is not valid hex string.")
-				return
-			}
+	if jsonBody.Side != "WHITE" && jsonBody.Side != "BLACK" {
+		return Game{}, newAPIError(http.StatusBadRequest, "invalid_side", "Side must be WHITE or BLACK.")
+	}
 
-			if ed25519.Verify(pubKey, []byte(gameID.String()), sig) {
-				fmt.Println("Player successfully joined as " + jsonBody.Side)
-				if jsonBody.Side == "WHITE" {
-					game.WhitePlayer = pubKey
-				}
-				if jsonBody.Side == "BLACK" {
-					game.BlackPlayer = pubKey
-				}
-				db.Save(&game)
+	if len(sideKey(*game, jsonBody.Side)) != 0 {
+		return Game{}, newAPIError(http.StatusConflict, "side_taken", "There's already a player for "+jsonBody.Side+".")
+	}
 
-				for _, sub := range socketSubs {
-					if sub.GameID == gameID {
-						sub.Conn.WriteJSON(game)
-					}
-				}
-			} else {
-				fmt.Println("Signature didn't verify properly.")
-				return
-			}
-		} else {
-			fmt.Println("There's already a player for " + jsonBody.Side)
-		}
+	fmt.Println("Nobody is playing " + jsonBody.Side + " currently, checking signature.")
+	sig, err := hex.DecodeString(jsonBody.Signed)
+	if err != nil {
+		return Game{}, newAPIError(http.StatusBadRequest, "invalid_signature_encoding", "Signature is not valid hex string.")
+	}
+
+	pubKey, err := hex.DecodeString(jsonBody.PubKey)
+	if err != nil {
+		return Game{}, newAPIError(http.StatusBadRequest, "invalid_public_key", "Public key is not valid hex string.")
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return Game{}, newAPIError(http.StatusBadRequest, "invalid_public_key", "Public key is the wrong length.")
+	}
+
+	if !ed25519.Verify(pubKey, []byte(gameID.String()), sig) {
+		return Game{}, newAPIError(http.StatusUnauthorized, "invalid_signature", "Signature didn't verify properly.")
+	}
+
+	fmt.Println("Player successfully joined as " + jsonBody.Side)
+	if jsonBody.Side == "WHITE" {
+		game.WhitePlayer = pubKey
+	}
+	if jsonBody.Side == "BLACK" {
+		game.BlackPlayer = pubKey
+	}
+	if !saveGame(actor) {
+		actor.conflict = true
+		return Game{}, errConflict
+	}
+	broadcast(actor, eventPlayerJoined, PlayerJoinedPayload{
+		Side: jsonBody.Side,
+		Game: *game,
 	})
+
+	return *game, nil
 }
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// TestMain runs the tests against a fresh sqlite database, so they never
+// touch the one a config.json in the working directory points at.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "chess-test")
+	check(err)
+	config = defaultConfig
+	config.DbConnectionStr = filepath.Join(dir, "chess.db")
+	config.Retention.ArchiveDir = filepath.Join(dir, "archive")
+	db = getDB(config)
+	broadcaster = newBroadcaster(config.Broadcast)
+
+	code := m.Run()
+
+	// db stays open until exit, since goroutines the tests started, such as
+	// presence updates, may still be using it
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testPosition sets up a game from fen the way the actor would.
+func testPosition(t *testing.T, fen string) (uuid.UUID, moveHistory) {
+	t.Helper()
+	position, err := parseFEN(fen)
+	if err != nil {
+		t.Fatalf("parseFEN(%q): %v", fen, err)
+	}
+	gameID := uuid.NewV4()
+	history := newMoveHistory(Game{StartFEN: fen})
+	history.record(startingBoardState(gameID, position))
+	return gameID, history
+}
+
+// submit is the board a client sends to move the piece on from to to,
+// optionally promoting it. Only the moved piece changes; the server
+// finishes castling and en passant itself.
+func submit(board [8][8]int, from string, to string, promotion int) [8][8]int {
+	start := stringToPos(from)
+	end := stringToPos(to)
+	piece := board[start[0]][start[1]]
+	if promotion != 0 {
+		piece = promotion
+	}
+	board[start[0]][start[1]] = empty
+	board[end[0]][end[1]] = piece
+	return board
+}
+
+func square(board [8][8]int, name string) int {
+	pos := stringToPos(name)
+	return board[pos[0]][pos[1]]
+}
+
+// storeGame stores a game so the actor can save changes to it.
+func storeGame(t *testing.T, game Game) Game {
+	t.Helper()
+	err := insertGame(db, &game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+var startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestPlayMoveViolations(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		from      string
+		to        string
+		promotion int
+		violation Violation
+		// moves played first, each as from and to
+		before [][2]string
+	}{
+		{"pawn push", startFEN, "E2", "E4", 0, ViolationNone, nil},
+		{"knight jump", startFEN, "G1", "F3", 0, ViolationNone, nil},
+		{"wrong color", startFEN, "E7", "E5", 0, ViolationWrongPieceColor, nil},
+		{"capture own piece", startFEN, "A1", "A2", 0, ViolationCaptureOwnPiece, nil},
+		{"knight moves straight", startFEN, "G1", "G3", 0, ViolationIllegalPieceMove, nil},
+		{"pawn pushes three", startFEN, "E2", "E5", 0, ViolationIllegalPieceMove, nil},
+		{"rook through pawn", "4k3/8/8/8/8/8/P7/R3K3 w - - 0 1", "A1", "A3", 0, ViolationBlockedPath, nil},
+		{"pinned bishop", "4k3/8/8/8/4r3/8/4B3/4K3 w - - 0 1", "E2", "D3", 0, ViolationLeavesKingInCheck, nil},
+		{"king into check", "4k3/8/8/8/8/8/3r4/4K3 w - - 0 1", "E1", "E2", 0, ViolationLeavesKingInCheck, nil},
+		{"pawn stays a pawn", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", 0, ViolationInvalidPromotion, nil},
+		{"promote to king", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", whiteKing, ViolationInvalidPromotion, nil},
+		{"promote to black queen", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", blackQueen, ViolationInvalidPromotion, nil},
+		{"promote to queen", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", whiteQueen, ViolationNone, nil},
+		{"underpromote to knight", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", whiteKnight, ViolationNone, nil},
+		{"castle after king moved", "r3k2r/8/8/8/8/8/8/R3K2R w kq - 0 1", "E1", "G1", 0, ViolationCastlePiecesMoved, nil},
+		{"castle after rook moved", "r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1", "E1", "G1", 0, ViolationCastlePiecesMoved, nil},
+		{"castle with rook taken at home", "4k3/8/8/8/8/8/6b1/4K2R b K - 0 1", "E1", "G1", 0, ViolationCastlePiecesMoved, [][2]string{{"G2", "H1"}}},
+		{"castle through check", "1k3r2/8/8/8/8/8/8/R3K2R w KQ - 0 1", "E1", "G1", 0, ViolationCastlingThroughCheck, nil},
+		{"castle into check", "1k4r1/8/8/8/8/8/8/R3K2R w KQ - 0 1", "E1", "G1", 0, ViolationLeavesKingInCheck, nil},
+		{"castle out of check", "1k2r3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "E1", "G1", 0, ViolationCastlingThroughCheck, nil},
+		{"castle with rook attacked", "1k5r/8/8/8/8/8/8/4K2R w K - 0 1", "E1", "G1", 0, ViolationNone, nil},
+		{"castle queenside with b1 attacked", "1r2k3/8/8/8/8/8/8/R3K3 w Q - 0 1", "E1", "C1", 0, ViolationNone, nil},
+		{"castle queenside past knight", "r3k2r/8/8/8/8/8/8/RN2K2R w KQkq - 0 1", "E1", "C1", 0, ViolationBlockedPath, nil},
+		{"castle onto knight", "r3k2r/8/8/8/8/8/8/R3K1nR w KQkq - 0 1", "E1", "G1", 0, ViolationBlockedPath, nil},
+		{"castle kingside", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "E1", "G1", 0, ViolationNone, nil},
+		{"castle queenside", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "E1", "C1", 0, ViolationNone, nil},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "E5", "D6", 0, ViolationNone, nil},
+		{"check beside an edge pawn", "4k3/8/8/P7/8/8/7r/4K3 b - - 0 1", "H2", "H1", 0, ViolationNone, nil},
+		{"en passant too late", "4k3/8/8/3pP3/8/8/8/4K3 w - - 0 1", "E5", "D6", 0, ViolationInvalidEnPassant, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameID, history := testPosition(t, test.fen)
+			for _, move := range test.before {
+				boardState, result := playMove(gameID, history.lastMove, submit(deserializeBoard(history.lastMove.State), move[0], move[1], 0), history)
+				if !result.Valid {
+					t.Fatalf("%s%s refused: %s", move[0], move[1], result.Violation)
+				}
+				history.record(boardState)
+			}
+			board := deserializeBoard(history.lastMove.State)
+			_, result := playMove(gameID, history.lastMove, submit(board, test.from, test.to, test.promotion), history)
+			if result.Violation != test.violation {
+				t.Errorf("got violation %q, want %q", result.Violation, test.violation)
+			}
+			if result.Valid != (test.violation == ViolationNone) {
+				t.Errorf("got valid %t with violation %q", result.Valid, result.Violation)
+			}
+		})
+	}
+}
+
+func TestPlayMoveInvalidBoard(t *testing.T) {
+	gameID, history := testPosition(t, startFEN)
+	board := deserializeBoard(history.lastMove.State)
+	board = submit(board, "E2", "E4", 0)
+	board = submit(board, "D2", "D4", 0)
+	_, result := playMove(gameID, history.lastMove, board, history)
+	if result.Violation != ViolationInvalidBoard {
+		t.Errorf("got violation %q, want %q", result.Violation, ViolationInvalidBoard)
+	}
+}
+
+func TestPlayMoveFinishesSpecialMoves(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		from     string
+		to       string
+		squares  map[string]int
+		castle   string
+		passant  bool
+		moveDiff int
+	}{
+		{
+			name:     "white kingside castle",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			from:     "E1",
+			to:       "G1",
+			squares:  map[string]int{"E1": empty, "F1": whiteRook, "G1": whiteKing, "H1": empty},
+			castle:   "KING",
+			moveDiff: 4,
+		},
+		{
+			name:     "white queenside castle",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			from:     "E1",
+			to:       "C1",
+			squares:  map[string]int{"A1": empty, "C1": whiteKing, "D1": whiteRook, "E1": empty},
+			castle:   "QUEEN",
+			moveDiff: 4,
+		},
+		{
+			name:     "black kingside castle",
+			fen:      "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			from:     "E8",
+			to:       "G8",
+			squares:  map[string]int{"E8": empty, "F8": blackRook, "G8": blackKing, "H8": empty},
+			castle:   "KING",
+			moveDiff: 4,
+		},
+		{
+			name:     "white takes en passant",
+			fen:      "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+			from:     "E5",
+			to:       "D6",
+			squares:  map[string]int{"D5": empty, "D6": whitePawn, "E5": empty},
+			passant:  true,
+			moveDiff: 3,
+		},
+		{
+			name:     "black takes en passant",
+			fen:      "4k3/8/8/8/3Pp3/8/8/4K3 b - d3 0 1",
+			from:     "E4",
+			to:       "D3",
+			squares:  map[string]int{"D4": empty, "D3": blackPawn, "E4": empty},
+			passant:  true,
+			moveDiff: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameID, history := testPosition(t, test.fen)
+			oldBoard := deserializeBoard(history.lastMove.State)
+			boardState, result := playMove(gameID, history.lastMove, submit(oldBoard, test.from, test.to, 0), history)
+			if !result.Valid {
+				t.Fatalf("move refused: %s", result.Violation)
+			}
+			if result.CastleType != test.castle || result.EnPassant != test.passant {
+				t.Errorf("got castle %q en passant %t", result.CastleType, result.EnPassant)
+			}
+			newBoard := deserializeBoard(boardState.State)
+			for name, piece := range test.squares {
+				if square(newBoard, name) != piece {
+					t.Errorf("%s holds %c, want %c", name, square(newBoard, name), piece)
+				}
+			}
+			if diffs := getSquareDiffs(oldBoard, newBoard); len(diffs) != test.moveDiff {
+				t.Errorf("got %d square diffs, want %d", len(diffs), test.moveDiff)
+			}
+		})
+	}
+}
+
+func TestToUCI(t *testing.T) {
+	tests := []struct {
+		fen       string
+		from      string
+		to        string
+		promotion int
+		uci       string
+	}{
+		{startFEN, "E2", "E4", 0, "e2e4"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "E1", "G1", 0, "e1g1"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", whiteQueen, "a7a8q"},
+		{"4k3/8/8/8/8/8/p7/4K3 b - - 0 1", "A2", "A1", blackKnight, "a2a1n"},
+	}
+
+	for _, test := range tests {
+		gameID, history := testPosition(t, test.fen)
+		board := deserializeBoard(history.lastMove.State)
+		boardState, result := playMove(gameID, history.lastMove, submit(board, test.from, test.to, test.promotion), history)
+		if !result.Valid {
+			t.Fatalf("%s%s refused: %s", test.from, test.to, result.Violation)
+		}
+		if uci := toUCI(boardState, deserializeBoard(boardState.State)); uci != test.uci {
+			t.Errorf("got %q, want %q", uci, test.uci)
+		}
+	}
+}
+
+func TestValidateMoveRefusesFinishedGames(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(actor *gameActor)
+		code   string
+		result string
+	}{
+		{"in play", func(*gameActor) {}, "", ""},
+		{"already over", func(actor *gameActor) { actor.game.Result = resultBlackWon }, "game_over", resultBlackWon},
+		{"flag fell", func(actor *gameActor) {
+			actor.clock.moves = 2
+			actor.clock.lastMoveAt = time.Now().Add(-2 * time.Minute)
+		}, "time_out", resultBlackWon},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameID, history := testPosition(t, startFEN)
+			game := storeGame(t, Game{GameID: gameID, InitialSeconds: 60})
+			actor := &gameActor{game: game, history: history, clock: newGameClock(game)}
+			test.setup(actor)
+			board := submit(deserializeBoard(history.lastMove.State), "E2", "E4", 0)
+			response, apiErr := validateMove(actor, board)
+			code := ""
+			if apiErr != nil {
+				code = apiErr.Code
+			}
+			if code != test.code || response.Valid != (test.code == "") {
+				t.Errorf("got valid %t error %q, want %q", response.Valid, code, test.code)
+			}
+			if actor.game.Result != test.result {
+				t.Errorf("got result %q, want %q", actor.game.Result, test.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CreateNonce is a single use challenge the creator of a game signs along
+// with their public key, so a creation signature can't be replayed.
+type CreateNonce struct {
+	Model
+	Nonce     string    `json:"nonce" gorm:"unique_index"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// how long a creator has to sign and use a nonce
+var createNonceLifetime = 5 * time.Minute
+
+// createMessage is what the creator of a game signs to be seated.
+func createMessage(nonce string, pubKey string) []byte {
+	return []byte("create:" + nonce + ":" + pubKey)
+}
+
+func issueCreateNonce() CreateNonce {
+	now := time.Now()
+	db.Unscoped().Where("expires_at <= ?", now).Delete(CreateNonce{})
+	nonce := CreateNonce{Nonce: newChallenge(), ExpiresAt: now.Add(createNonceLifetime)}
+	check(db.Create(&nonce).Error)
+	return nonce
+}
+
+// useCreateNonce spends a nonce, reporting whether it was issued, unused and
+// unexpired. Deleting the row is what makes it single use across instances.
+func useCreateNonce(nonce string) bool {
+	if nonce == "" {
+		return false
+	}
+	return db.Unscoped().Where("nonce = ? AND expires_at > ?", nonce, time.Now()).Delete(CreateNonce{}).RowsAffected == 1
+}
+
+// GameNonceHandler issues a nonce for creating a game with a seat.
+func GameNonceHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+		writeJSON(res, req, issueCreateNonce())
+	})
+}
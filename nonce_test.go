@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCreateNonce(t *testing.T) {
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	hexKey := hex.EncodeToString(pubKey)
+	sign := func(message []byte) string {
+		return hex.EncodeToString(ed25519.Sign(privKey, message))
+	}
+	used := issueCreateNonce().Nonce
+	if _, apiErr := (&GameOptions{PubKey: hexKey, Nonce: used, Signed: sign(createMessage(used, hexKey))}).validate(); apiErr != nil {
+		t.Fatalf("first use: %v", apiErr.Message)
+	}
+
+	tests := []struct {
+		name  string
+		nonce string
+		sign  func(nonce string) string
+		code  string
+	}{
+		{"fresh nonce", issueCreateNonce().Nonce, func(nonce string) string { return sign(createMessage(nonce, hexKey)) }, ""},
+		{"reused nonce", used, func(nonce string) string { return sign(createMessage(nonce, hexKey)) }, "invalid_nonce"},
+		{"unknown nonce", newChallenge(), func(nonce string) string { return sign(createMessage(nonce, hexKey)) }, "invalid_nonce"},
+		{"no nonce", "", func(nonce string) string { return sign(createMessage(nonce, hexKey)) }, "invalid_nonce"},
+		{"old signature of the key", issueCreateNonce().Nonce, func(string) string { return sign([]byte(hexKey)) }, "invalid_signature"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := GameOptions{PubKey: hexKey, Nonce: test.nonce, Signed: test.sign(test.nonce)}
+			_, apiErr := options.validate()
+			code := ""
+			if apiErr != nil {
+				code = apiErr.Code
+			}
+			if code != test.code {
+				t.Errorf("got %q, want %q", code, test.code)
+			}
+		})
+	}
+}
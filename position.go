@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+)
+
+// PlyResponse is a response to the /game/{id}/ply/{n} endpoint.
+type PlyResponse struct {
+	GameID         uuid.UUID `json:"gameID"`
+	Ply            int       `json:"ply"`
+	Board          [8][8]int `json:"board"`
+	SideToMove     string    `json:"sideToMove"`
+	LastMove       *MoveInfo `json:"lastMove"`
+	HalfmoveClock  int       `json:"halfmoveClock"`
+	FullmoveNumber int       `json:"fullmoveNumber"`
+	FEN            string    `json:"fen"`
+}
+
+// Position is a game at a single ply, with the counters FEN needs.
+type Position struct {
+	Board          [8][8]int
+	SideToMove     string
+	Castling       string
+	EnPassant      string
+	HalfmoveClock  int
+	FullmoveNumber int
+}
+
+func stringToPos(square string) [2]int {
+	if len(square) != 2 {
+		panic("square must be of length 2")
+	}
+	col := int(strings.ToUpper(square)[0] - 'A')
+	row := int('8' - square[1])
+	return [2]int{row, col}
+}
+
+// replayPosition builds the position after the last of boardStates, which
+// must start from the game's starting board.
+func replayPosition(game Game, boardStates []BoardState) Position {
+	start, err := startingPosition(game.StartFEN)
+	check(err)
+	movedFrom := newMoveHistory(game).movedFrom
+	halfmoveClock := start.HalfmoveClock
+	fullmoveNumber := start.FullmoveNumber
+	for _, boardState := range boardStates[1:] {
+		movedFrom[boardState.StartPosition] = true
+		if boardState.PieceTaken != 0 || boardState.PieceMoved == whitePawn || boardState.PieceMoved == blackPawn {
+			halfmoveClock = 0
+		} else {
+			halfmoveClock++
+		}
+		if boardState.MoveAuthor == "BLACK" {
+			fullmoveNumber++
+		}
+	}
+
+	last := boardStates[len(boardStates)-1]
+	board := deserializeBoard(last.State)
+
+	castling := ""
+	if board[7][4] == whiteKing && !movedFrom["E1"] {
+		if board[7][7] == whiteRook && !movedFrom["H1"] {
+			castling += "K"
+		}
+		if board[7][0] == whiteRook && !movedFrom["A1"] {
+			castling += "Q"
+		}
+	}
+	if board[0][4] == blackKing && !movedFrom["E8"] {
+		if board[0][7] == blackRook && !movedFrom["H8"] {
+			castling += "k"
+		}
+		if board[0][0] == blackRook && !movedFrom["A8"] {
+			castling += "q"
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+
+	enPassant := "-"
+	if (last.PieceMoved == whitePawn || last.PieceMoved == blackPawn) && last.StartPosition != "" {
+		startPos := stringToPos(last.StartPosition)
+		endPos := stringToPos(last.EndPosition)
+		if startPos[0]-endPos[0] == 2 || startPos[0]-endPos[0] == -2 {
+			enPassant = squareName([2]int{(startPos[0] + endPos[0]) / 2, startPos[1]})
+		}
+	}
+
+	return Position{
+		Board:          board,
+		SideToMove:     nextMoveAuthor(last),
+		Castling:       castling,
+		EnPassant:      enPassant,
+		HalfmoveClock:  halfmoveClock,
+		FullmoveNumber: fullmoveNumber,
+	}
+}
+
+var fenPieces = map[rune]int{
+	'P': whitePawn, 'N': whiteKnight, 'B': whiteBishop, 'R': whiteRook, 'Q': whiteQueen, 'K': whiteKing,
+	'p': blackPawn, 'n': blackKnight, 'b': blackBishop, 'r': blackRook, 'q': blackQueen, 'k': blackKing,
+}
+
+// castlingSquares maps each castling right to the rook square it depends on.
+var castlingSquares = map[string]string{"K": "H1", "Q": "A1", "k": "H8", "q": "A8"}
+
+// lostCastlingSquares lists the rook squares that have no castling right in
+// a starting FEN, so they can be treated as already moved.
+func lostCastlingSquares(fen string) []string {
+	squares := []string{}
+	fields := strings.Fields(fen)
+	if len(fields) < 3 {
+		return squares
+	}
+	for right, square := range castlingSquares {
+		if !strings.Contains(fields[2], right) {
+			squares = append(squares, square)
+		}
+	}
+	return squares
+}
+
+// parseFEN reads a starting position. The halfmove and fullmove fields are
+// optional.
+func parseFEN(fen string) (Position, error) {
+	position := Position{
+		HalfmoveClock:  0,
+		FullmoveNumber: 1,
+	}
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return position, fmt.Errorf("FEN must have 4 or 6 fields")
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return position, fmt.Errorf("FEN must have 8 ranks")
+	}
+	kings := map[int]int{}
+	for i, rank := range ranks {
+		j := 0
+		for _, char := range rank {
+			if char >= '1' && char <= '8' {
+				for n := 0; n < int(char-'0'); n++ {
+					if j >= 8 {
+						return position, fmt.Errorf("rank %q does not have 8 squares", rank)
+					}
+					position.Board[i][j] = empty
+					j++
+				}
+				continue
+			}
+			piece, ok := fenPieces[char]
+			if !ok || j >= 8 {
+				return position, fmt.Errorf("invalid rank %q", rank)
+			}
+			if (piece == whitePawn || piece == blackPawn) && (i == 0 || i == 7) {
+				return position, fmt.Errorf("pawns can not be on the first or last rank")
+			}
+			kings[piece]++
+			position.Board[i][j] = piece
+			j++
+		}
+		if j != 8 {
+			return position, fmt.Errorf("rank %q does not have 8 squares", rank)
+		}
+	}
+	if kings[whiteKing] != 1 || kings[blackKing] != 1 {
+		return position, fmt.Errorf("each side must have exactly one king")
+	}
+
+	switch fields[1] {
+	case "w":
+		position.SideToMove = "WHITE"
+	case "b":
+		position.SideToMove = "BLACK"
+	default:
+		return position, fmt.Errorf("side to move must be w or b")
+	}
+	if checkStatus(position.Board, nextMoveAuthor(BoardState{MoveAuthor: position.SideToMove})) {
+		return position, fmt.Errorf("the side not to move is in check")
+	}
+
+	position.Castling = fields[2]
+	if position.Castling != "-" {
+		for _, right := range position.Castling {
+			rookSquare, ok := castlingSquares[string(right)]
+			if !ok {
+				return position, fmt.Errorf("invalid castling rights %q", position.Castling)
+			}
+			king, rook := whiteKing, whiteRook
+			kingPos := stringToPos("E1")
+			if right == 'k' || right == 'q' {
+				king, rook = blackKing, blackRook
+				kingPos = stringToPos("E8")
+			}
+			rookPos := stringToPos(rookSquare)
+			if position.Board[kingPos[0]][kingPos[1]] != king || position.Board[rookPos[0]][rookPos[1]] != rook {
+				return position, fmt.Errorf("castling rights %q need the king and rook on their starting squares", string(right))
+			}
+		}
+	}
+
+	position.EnPassant = fields[3]
+	if position.EnPassant != "-" {
+		if len(position.EnPassant) != 2 || position.EnPassant[0] < 'a' || position.EnPassant[0] > 'h' {
+			return position, fmt.Errorf("invalid en passant square %q", position.EnPassant)
+		}
+		if (position.SideToMove == "WHITE" && position.EnPassant[1] != '6') || (position.SideToMove == "BLACK" && position.EnPassant[1] != '3') {
+			return position, fmt.Errorf("invalid en passant square %q", position.EnPassant)
+		}
+		// the pawn that just moved two squares must be in front of the square
+		target := stringToPos(position.EnPassant)
+		if position.SideToMove == "WHITE" && position.Board[target[0]+1][target[1]] != blackPawn ||
+			position.SideToMove == "BLACK" && position.Board[target[0]-1][target[1]] != whitePawn {
+			return position, fmt.Errorf("no pawn can be taken en passant on %q", position.EnPassant)
+		}
+	}
+
+	if len(fields) == 6 {
+		halfmoveClock, err := strconv.Atoi(fields[4])
+		if err != nil || halfmoveClock < 0 {
+			return position, fmt.Errorf("invalid halfmove clock")
+		}
+		fullmoveNumber, err := strconv.Atoi(fields[5])
+		if err != nil || fullmoveNumber < 1 {
+			return position, fmt.Errorf("invalid fullmove number")
+		}
+		position.HalfmoveClock = halfmoveClock
+		position.FullmoveNumber = fullmoveNumber
+	}
+
+	return position, nil
+}
+
+// startingBoardState is the first board state of a game starting from
+// position. A FEN en passant square is stored as the double pawn push that
+// allows it, so the rules see it like any other last move.
+func startingBoardState(gameID uuid.UUID, position Position) BoardState {
+	boardState := BoardState{
+		GameID:     gameID,
+		State:      serializeBoard(position.Board),
+		MoveAuthor: nextMoveAuthor(BoardState{MoveAuthor: position.SideToMove}),
+	}
+	if position.EnPassant != "-" {
+		target := stringToPos(position.EnPassant)
+		if position.SideToMove == "WHITE" {
+			boardState.PieceMoved = blackPawn
+			boardState.StartPosition = posToString([2]int{target[0] - 1, target[1]})
+			boardState.EndPosition = posToString([2]int{target[0] + 1, target[1]})
+		} else {
+			boardState.PieceMoved = whitePawn
+			boardState.StartPosition = posToString([2]int{target[0] + 1, target[1]})
+			boardState.EndPosition = posToString([2]int{target[0] - 1, target[1]})
+		}
+	}
+	return boardState
+}
+
+// FEN writes the position in Forsyth-Edwards Notation.
+func (position Position) FEN() string {
+	ranks := []string{}
+	for _, row := range position.Board {
+		rank := ""
+		empties := 0
+		for _, square := range row {
+			if square == empty {
+				empties++
+				continue
+			}
+			if empties > 0 {
+				rank += strconv.Itoa(empties)
+				empties = 0
+			}
+			// pieces are stored as their FEN letters
+			rank += string(rune(square))
+		}
+		if empties > 0 {
+			rank += strconv.Itoa(empties)
+		}
+		ranks = append(ranks, rank)
+	}
+
+	side := "w"
+	if position.SideToMove == "BLACK" {
+		side = "b"
+	}
+
+	return strings.Join([]string{
+		strings.Join(ranks, "/"),
+		side,
+		position.Castling,
+		position.EnPassant,
+		strconv.Itoa(position.HalfmoveClock),
+		strconv.Itoa(position.FullmoveNumber),
+	}, " ")
+}
+
+// GamePlyHandler returns a game as it was at a single ply.
+func GamePlyHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+
+		vars := mux.Vars(req)
+		gameID, apiErr := resolveGameID(vars["id"])
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		ply, apiErr := intParam(vars["n"], 0)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+
+		boardStates := []BoardState{}
+		db.Where("game_id = ?", gameID).Order("id").Limit(ply + 1).Find(&boardStates)
+		if len(boardStates) == 0 {
+			writeError(res, req, errGameNotFound)
+			return
+		}
+		if len(boardStates) <= ply {
+			writeError(res, req, newAPIError(http.StatusNotFound, "ply_not_found", "Game has not reached ply "+strconv.Itoa(ply)+"."))
+			return
+		}
+
+		// a game never changes at a ply it has already reached
+		if notModified(res, req, gameETag(req, strconv.Itoa(ply)), boardStates[ply].CreatedAt, true) {
+			return
+		}
+
+		game := Game{}
+		db.First(&game, "game_id = ?", gameID)
+		position := replayPosition(game, boardStates)
+		response := PlyResponse{
+			GameID:         gameID,
+			Ply:            ply,
+			Board:          position.Board,
+			SideToMove:     position.SideToMove,
+			HalfmoveClock:  position.HalfmoveClock,
+			FullmoveNumber: position.FullmoveNumber,
+			FEN:            position.FEN(),
+		}
+		if ply > 0 {
+			lastMove := moveInfo(ply, boardStates[ply])
+			response.LastMove = &lastMove
+		}
+
+		writeJSON(res, req, response)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		startFEN,
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+		"8/8/8/8/8/5k2/8/4K2R w K - 99 120",
+	}
+	for _, fen := range fens {
+		position, err := parseFEN(fen)
+		if err != nil {
+			t.Errorf("parseFEN(%q): %v", fen, err)
+			continue
+		}
+		if got := position.FEN(); got != fen {
+			t.Errorf("parseFEN(%q).FEN() = %q", fen, got)
+		}
+	}
+}
+
+func TestParseFENDefaultsCounters(t *testing.T) {
+	position, err := parseFEN("4k3/8/8/8/8/8/8/4K3 b - -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.HalfmoveClock != 0 || position.FullmoveNumber != 1 || position.SideToMove != "BLACK" {
+		t.Errorf("got %+v", position)
+	}
+}
+
+func TestParseFENErrors(t *testing.T) {
+	tests := []struct {
+		fen string
+		err string
+	}{
+		{"4k3/8/8/8/8/8/8/4K3", "4 or 6 fields"},
+		{"4k3/8/8/8/8/8/4K3 w - - 0 1", "8 ranks"},
+		{"4k3/8/8/8/8/8/8/4K2X w - - 0 1", "invalid rank"},
+		{"4k3/8/8/8/8/8/8/4K4 w - - 0 1", "8 squares"},
+		{"P3k3/8/8/8/8/8/8/4K3 w - - 0 1", "first or last rank"},
+		{"8/8/8/8/8/8/8/4K3 w - - 0 1", "exactly one king"},
+		{"4k3/8/8/8/8/8/8/3KK3 w - - 0 1", "exactly one king"},
+		{"4k3/8/8/8/8/8/8/4K3 x - - 0 1", "w or b"},
+		{"4k3/8/8/8/8/8/8/4K2R b - - 0 1", ""},
+		{"4k3/4R3/8/8/8/8/8/4K3 w - - 0 1", "not to move is in check"},
+		{"4k3/8/8/8/8/8/8/4K3 w X - 0 1", "invalid castling"},
+		{"4k3/8/8/8/8/8/8/4K3 w K - 0 1", "starting squares"},
+		{"r3k2r/8/8/8/8/8/8/R4K1R w Q - 0 1", "starting squares"},
+		{"4k3/8/8/8/8/8/8/4K3 w - e9 0 1", "invalid en passant"},
+		{"4k3/8/8/8/8/8/8/4K3 w - e3 0 1", "invalid en passant"},
+		{"4k3/8/8/8/8/8/8/4K3 w - d6 0 1", "en passant"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - x 1", "halfmove"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 0", "fullmove"},
+	}
+	for _, test := range tests {
+		_, err := parseFEN(test.fen)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("parseFEN(%q): %v", test.fen, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("parseFEN(%q) = %v, want error containing %q", test.fen, err, test.err)
+		}
+	}
+}
+
+func TestLostCastlingSquares(t *testing.T) {
+	gameID, history := testPosition(t, "r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1")
+	board := deserializeBoard(history.lastMove.State)
+	for _, move := range [][2]string{{"E1", "C1"}, {"E1", "G1"}} {
+		_, result := playMove(gameID, history.lastMove, submit(board, move[0], move[1], 0), history)
+		want := ViolationNone
+		if move[1] == "C1" {
+			want = ViolationCastlePiecesMoved
+		}
+		if result.Violation != want {
+			t.Errorf("%s%s: got %q, want %q", move[0], move[1], result.Violation, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Presence is who has a websocket open to a game. Players count as
+// connected once a socket has authenticated as their side; every other
+// socket is watching.
+type Presence struct {
+	WhiteConnected bool `json:"whiteConnected"`
+	BlackConnected bool `json:"blackConnected"`
+	Watching       int  `json:"watching"`
+}
+
+// NodePresence is who one instance has sockets open for in a game. With the
+// database backplane each instance keeps its own rows up to date, so any
+// instance can report who is connected across all of them.
+type NodePresence struct {
+	Model
+	GameID         uuid.UUID `gorm:"unique_index:idx_node_presence_game_node"`
+	Node           string    `gorm:"unique_index:idx_node_presence_game_node"`
+	WhiteConnected bool
+	BlackConnected bool
+	Watching       int
+	// when the counts last changed; UpdatedAt is kept fresh while the
+	// instance is running
+	ChangedAt time.Time
+}
+
+// PresencePayload is sent when a player connects or disconnects.
+type PresencePayload struct {
+	Side      string `json:"side"`
+	Connected bool   `json:"connected"`
+}
+
+// when each game with sockets open last had one come or go, for
+// Last-Modified; guarded by socketSubsLock
+var presenceChanged = map[uuid.UUID]time.Time{}
+
+// when any game last lost its final socket. Games without sockets report
+// this rather than keeping an entry each, so Last-Modified never goes back
+// in time when one empties.
+var presenceEmptied time.Time
+
+func gamePresence(gameID uuid.UUID) (Presence, time.Time) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	presence := Presence{}
+	for sub := range socketSubs[gameID] {
+		switch sub.audience {
+		case "WHITE":
+			presence.WhiteConnected = true
+		case "BLACK":
+			presence.BlackConnected = true
+		default:
+			presence.Watching++
+		}
+	}
+	changed, ok := presenceChanged[gameID]
+	if !ok {
+		changed = presenceEmptied
+	}
+	return presence, changed
+}
+
+func (presence Presence) connected(side string) bool {
+	if side == "WHITE" {
+		return presence.WhiteConnected
+	}
+	return presence.BlackConnected
+}
+
+// updatePresence shares who is connected to this instance, then tells
+// everyone watching about players who have connected or disconnected, on
+// any instance, since it last ran. Running on the actor keeps the shared
+// counts in the order the sockets changed.
+func updatePresence(actor *gameActor) {
+	gameID := actor.game.GameID
+	local, _ := gamePresence(gameID)
+	broadcaster.SharePresence(gameID, local)
+	presence, _ := broadcaster.Presence(gameID)
+	for _, side := range []string{"WHITE", "BLACK"} {
+		if presence.connected(side) == actor.connected[side] {
+			continue
+		}
+		actor.connected[side] = presence.connected(side)
+		broadcast(actor, eventPresence, PresencePayload{
+			Side:      side,
+			Connected: presence.connected(side),
+		})
+	}
+}
+
+func forgetPresence(gameID uuid.UUID) {
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	delete(presenceChanged, gameID)
+}
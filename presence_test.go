@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestPresenceForgottenWhenLastSocketLeaves(t *testing.T) {
+	gameID := uuid.NewV4()
+	subs := []*SocketSub{}
+	for i := 0; i < 2; i++ {
+		if apiErr := reserveConnection(gameID, "192.0.2.1"); apiErr != nil {
+			t.Fatal(apiErr.Message)
+		}
+		subs = append(subs, subscribeSocket(gameID, "192.0.2.1", nil, false))
+	}
+
+	subs[0].unsubscribe(websocket.CloseNormalClosure, "")
+	presence, _ := gamePresence(gameID)
+	if presence.Watching != 1 {
+		t.Errorf("got %d watching, want 1", presence.Watching)
+	}
+
+	left := time.Now()
+	subs[1].unsubscribe(websocket.CloseNormalClosure, "")
+	presence, changed := gamePresence(gameID)
+	if presence.Watching != 0 {
+		t.Errorf("got %d watching, want 0", presence.Watching)
+	}
+	if changed.Before(left) {
+		t.Errorf("last change %v is before the last socket left at %v", changed, left)
+	}
+
+	socketSubsLock.Lock()
+	defer socketSubsLock.Unlock()
+	if _, ok := presenceChanged[gameID]; ok {
+		t.Error("presence is still tracked for a game with no sockets")
+	}
+	if connectionsPerGame[gameID] != 0 {
+		t.Errorf("got %d connections still reserved", connectionsPerGame[gameID])
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// RetentionConfig controls how long games are kept around.
+type RetentionConfig struct {
+	IntervalMinutes     int    `json:"intervalMinutes"`
+	UnjoinedGameHours   int    `json:"unjoinedGameHours"`
+	ArchiveFinishedDays int    `json:"archiveFinishedDays"`
+	ArchiveDir          string `json:"archiveDir"`
+	DryRun              bool   `json:"dryRun"`
+}
+
+// RetentionReport lists the games removed by a retention run.
+type RetentionReport struct {
+	DryRun   bool        `json:"dryRun"`
+	Deleted  []uuid.UUID `json:"deleted"`
+	Archived []uuid.UUID `json:"archived"`
+}
+
+// GameArchive is a game and its full history, as written to disk.
+type GameArchive struct {
+	Version     int          `json:"version"`
+	Game        Game         `json:"game"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+	BoardStates []BoardState `json:"boardStates"`
+	// when each board state was recorded, since BoardState doesn't say
+	MoveTimes []time.Time     `json:"moveTimes"`
+	Events    []ArchivedEvent `json:"events"`
+}
+
+// ArchivedEvent is a stored event, kept so sequences carry on after a
+// restore.
+type ArchivedEvent struct {
+	Sequence  int             `json:"sequence"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// version 1 archives have no timestamps or events
+var gameArchiveVersion = 2
+
+func retentionJob() {
+	interval := time.Duration(config.Retention.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		return
+	}
+	for {
+		report := runRetention(config.Retention, time.Now())
+		byteRes, err := json.Marshal(report)
+		check(err)
+		fmt.Println("Retention run: " + string(byteRes))
+		time.Sleep(interval)
+	}
+}
+
+func runRetention(retention RetentionConfig, now time.Time) RetentionReport {
+	report := RetentionReport{
+		DryRun:   retention.DryRun,
+		Deleted:  []uuid.UUID{},
+		Archived: []uuid.UUID{},
+	}
+
+	if retention.UnjoinedGameHours > 0 {
+		cutoff := now.Add(-time.Duration(retention.UnjoinedGameHours) * time.Hour)
+		games := []Game{}
+		db.Where("created_at < ?", cutoff).Find(&games)
+		for _, game := range games {
+			if len(game.WhitePlayer) != 0 || len(game.BlackPlayer) != 0 {
+				continue
+			}
+			removed := whileInactive(game.GameID, func() bool {
+				if !retention.DryRun {
+					purgeGame(game.GameID)
+				}
+				return true
+			})
+			if removed {
+				report.Deleted = append(report.Deleted, game.GameID)
+			}
+		}
+	}
+
+	if retention.ArchiveFinishedDays > 0 {
+		cutoff := now.Add(-time.Duration(retention.ArchiveFinishedDays) * 24 * time.Hour)
+		finished := []BoardState{}
+		db.Where("check_mate = ? AND created_at < ?", true, cutoff).Find(&finished)
+		gameIDs := []uuid.UUID{}
+		for _, boardState := range finished {
+			gameIDs = append(gameIDs, boardState.GameID)
+		}
+		// resigned and drawn games finish without a checkmate
+		ended := []Game{}
+		db.Where("result IS NOT NULL AND result != '' AND updated_at < ?", cutoff).Find(&ended)
+		for _, game := range ended {
+			if game.ResultReason != "checkmate" {
+				gameIDs = append(gameIDs, game.GameID)
+			}
+		}
+		for _, gameID := range gameIDs {
+			archived := whileInactive(gameID, func() bool {
+				if retention.DryRun {
+					return true
+				}
+				err := archiveGame(gameID, retention.ArchiveDir)
+				if err != nil {
+					fmt.Println("Failed to archive game " + gameID.String() + ": " + err.Error())
+					return false
+				}
+				purgeGame(gameID)
+				return true
+			})
+			if archived {
+				report.Archived = append(report.Archived, gameID)
+			}
+		}
+	}
+
+	return report
+}
+
+// whileInactive runs op unless the game is held in memory, keeping it from
+// being loaded until op returns. Other games can be loaded meanwhile.
+func whileInactive(gameID uuid.UUID, op func() bool) bool {
+	actorsLock.Lock()
+	if _, ok := actors[gameID]; ok {
+		actorsLock.Unlock()
+		return false
+	}
+	if _, ok := retiring[gameID]; ok {
+		actorsLock.Unlock()
+		return false
+	}
+	released := make(chan struct{})
+	retiring[gameID] = released
+	actorsLock.Unlock()
+
+	defer func() {
+		actorsLock.Lock()
+		delete(retiring, gameID)
+		// the game may be gone now, so loads that read it meanwhile retry
+		actorsStopped++
+		actorsLock.Unlock()
+		close(released)
+	}()
+	return op()
+}
+
+func loadGameArchive(gameID uuid.UUID) (GameArchive, bool) {
+	game := Game{}
+	if db.First(&game, "game_id = ?", gameID).RecordNotFound() {
+		return GameArchive{}, false
+	}
+	boardStates := []BoardState{}
+	db.Where("game_id = ?", gameID).Order("id").Find(&boardStates)
+	moveTimes := []time.Time{}
+	for _, boardState := range boardStates {
+		moveTimes = append(moveTimes, boardState.CreatedAt)
+	}
+	stored := []StoredEvent{}
+	db.Where("game_id = ?", gameID).Order("sequence").Find(&stored)
+	events := []ArchivedEvent{}
+	for _, event := range stored {
+		events = append(events, ArchivedEvent{
+			Sequence:  event.Sequence,
+			Type:      event.Type,
+			Payload:   event.Payload,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return GameArchive{
+		Version:     gameArchiveVersion,
+		Game:        game,
+		CreatedAt:   game.CreatedAt,
+		UpdatedAt:   game.UpdatedAt,
+		BoardStates: boardStates,
+		MoveTimes:   moveTimes,
+		Events:      events,
+	}, true
+}
+
+func archiveGame(gameID uuid.UUID, archiveDir string) error {
+	archive, ok := loadGameArchive(gameID)
+	if !ok {
+		return fmt.Errorf("game not found")
+	}
+
+	err := os.MkdirAll(archiveDir, 0700)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(archiveDir, gameID.String()+".json.gz"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zipper := gzip.NewWriter(file)
+	err = json.NewEncoder(zipper).Encode(archive)
+	if err != nil {
+		return err
+	}
+	return zipper.Close()
+}
+
+func purgeGame(gameID uuid.UUID) {
+	db.Unscoped().Where("game_id = ?", gameID).Delete(BoardState{})
+	db.Unscoped().Where("game_id = ?", gameID).Delete(StoredEvent{})
+	db.Unscoped().Where("game_id = ?", gameID).Delete(NodePresence{})
+	db.Unscoped().Where("game_id = ?", gameID).Delete(Game{})
+	forgetPresence(gameID)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+)
+
+var pieceLetters = map[int]string{
+	whiteKnight: "N", blackKnight: "N",
+	whiteBishop: "B", blackBishop: "B",
+	whiteRook: "R", blackRook: "R",
+	whiteQueen: "Q", blackQueen: "Q",
+	whiteKing: "K", blackKing: "K",
+}
+
+func squareName(pos [2]int) string {
+	return strings.ToLower(posToString(pos))
+}
+
+// toSAN writes a legal move in standard algebraic notation.
+func toSAN(oldState [8][8]int, newState [8][8]int, result MoveResult, history moveHistory) string {
+	san := ""
+	switch {
+	case result.CastleType == "KING":
+		san = "O-O"
+	case result.CastleType == "QUEEN":
+		san = "O-O-O"
+	case result.PieceMoved == whitePawn || result.PieceMoved == blackPawn:
+		captured := result.EnPassant || oldState[result.EndPos[0]][result.EndPos[1]] != empty
+		if captured {
+			san = squareName(result.StartPos)[:1] + "x"
+		}
+		san += squareName(result.EndPos)
+		promoted := newState[result.EndPos[0]][result.EndPos[1]]
+		if promoted != result.PieceMoved {
+			san += "=" + pieceLetters[promoted]
+		}
+	default:
+		san = pieceLetters[result.PieceMoved] + disambiguation(oldState, result, history)
+		if oldState[result.EndPos[0]][result.EndPos[1]] != empty {
+			san += "x"
+		}
+		san += squareName(result.EndPos)
+	}
+
+	if result.CheckMate {
+		return san + "#"
+	}
+	if result.Check {
+		return san + "+"
+	}
+	return san
+}
+
+// disambiguation returns the file, rank or square needed to tell the moved
+// piece apart from another of the same kind that could legally reach the
+// same square.
+func disambiguation(oldState [8][8]int, result MoveResult, history moveHistory) string {
+	sameFile := false
+	sameRank := false
+	ambiguous := false
+	for i, row := range oldState {
+		for j, piece := range row {
+			other := [2]int{i, j}
+			if piece != result.PieceMoved || other == result.StartPos {
+				continue
+			}
+			for _, mov := range legalMoves(other, oldState, history) {
+				// a pinned piece can't make the move, so doesn't count
+				if mov != result.EndPos || checkStatus(movePiece(oldState, other, mov), pieceColor(piece)) {
+					continue
+				}
+				ambiguous = true
+				if j == result.StartPos[1] {
+					sameFile = true
+				}
+				if i == result.StartPos[0] {
+					sameRank = true
+				}
+			}
+		}
+	}
+
+	start := squareName(result.StartPos)
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return start[:1]
+	case !sameRank:
+		return start[1:]
+	default:
+		return start
+	}
+}
+
+// toUCI writes a move the way UCI engines do, from and to squares plus the
+// piece a pawn promoted to. Castling is the king's move.
+func toUCI(boardState BoardState, newState [8][8]int) string {
+	uci := strings.ToLower(boardState.StartPosition + boardState.EndPosition)
+	if boardState.PieceMoved != whitePawn && boardState.PieceMoved != blackPawn {
+		return uci
+	}
+	endPos := stringToPos(boardState.EndPosition)
+	promoted := newState[endPos[0]][endPos[1]]
+	if promoted != boardState.PieceMoved {
+		uci += strings.ToLower(pieceLetters[promoted])
+	}
+	return uci
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestToSAN(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		from      string
+		to        string
+		promotion int
+		san       string
+	}{
+		{"pawn push", startFEN, "E2", "E4", 0, "e4"},
+		{"knight", startFEN, "G1", "F3", 0, "Nf3"},
+		{"pawn capture", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "E4", "D5", 0, "exd5"},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "E5", "D6", 0, "exd6"},
+		{"piece capture", "4k3/8/8/3p4/8/8/8/3QK3 w - - 0 1", "D1", "D5", 0, "Qxd5"},
+		{"kingside castle", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "E1", "G1", 0, "O-O"},
+		{"queenside castle", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "E8", "C8", 0, "O-O-O"},
+		{"promotion", "8/P3k3/8/8/8/8/8/4K3 w - - 0 1", "A7", "A8", whiteQueen, "a8=Q"},
+		{"capture and promote", "1r6/P3k3/8/8/8/8/8/4K3 w - - 0 1", "A7", "B8", whiteKnight, "axb8=N"},
+		{"check", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "A1", "A8", 0, "Ra8+"},
+		{"checkmate", "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", "A1", "A8", 0, "Ra8#"},
+		{"file disambiguation", "4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "B1", "D2", 0, "Nbd2"},
+		{"rank disambiguation", "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "A1", "A3", 0, "R1a3"},
+		{"square disambiguation", "4k3/8/8/8/8/Q1Q5/8/Q3K3 w - - 0 1", "A3", "B2", 0, "Qa3b2"},
+		{"pinned twin needs none", "k3r3/8/8/8/8/8/4N3/1N2K3 w - - 0 1", "B1", "C3", 0, "Nc3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameID, history := testPosition(t, test.fen)
+			oldBoard := deserializeBoard(history.lastMove.State)
+			boardState, result := playMove(gameID, history.lastMove, submit(oldBoard, test.from, test.to, test.promotion), history)
+			if !result.Valid {
+				t.Fatalf("move refused: %s", result.Violation)
+			}
+			san := toSAN(oldBoard, deserializeBoard(boardState.State), result, history)
+			if san != test.san {
+				t.Errorf("got %q, want %q", san, test.san)
+			}
+		})
+	}
+}
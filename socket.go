@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// SocketRequest is a message from a client over the websocket. ID is
+// chosen by the client and echoed back in the response.
+type SocketRequest struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// join
+	PubKey string `json:"pubKey,omitempty"`
+	// auth, join, move, resign, draw and chat
+	Signed string `json:"signed,omitempty"`
+	// auth, join, resign, draw and chat
+	Side string `json:"side,omitempty"`
+	// move
+	State [8][8]int `json:"state"`
+	// chat
+	Text string `json:"text,omitempty"`
+	// subscribe
+	LastSequence int `json:"lastSequence,omitempty"`
+}
+
+// SubscribeResult acknowledges a subscribe request with the sequence of the
+// last event the client has now been sent.
+type SubscribeResult struct {
+	Sequence int `json:"sequence"`
+}
+
+// SocketResponse answers a single SocketRequest, with either a result or
+// an error.
+type SocketResponse struct {
+	Type   string      `json:"type"`
+	ID     string      `json:"id"`
+	Result interface{} `json:"result,omitempty"`
+	Error  *APIError   `json:"error,omitempty"`
+}
+
+func ackSocket(id string, result interface{}) SocketResponse {
+	return SocketResponse{
+		Type:   "ack",
+		ID:     id,
+		Result: result,
+	}
+}
+
+func errorSocket(id string, apiErr *APIError) SocketResponse {
+	return SocketResponse{
+		Type:  "error",
+		ID:    id,
+		Error: apiErr,
+	}
+}
+
+// handleSocketRequest runs a websocket request against a game, the same
+// way the http endpoints would.
+func handleSocketRequest(sub *SocketSub, request SocketRequest) SocketResponse {
+	gameID := sub.GameID
+	fmt.Println("socket", request.Type, gameID)
+
+	var result interface{}
+	var apiErr *APIError
+	var op func(actor *gameActor)
+
+	action := ActionRequest{
+		Side:   request.Side,
+		Signed: request.Signed,
+		Text:   request.Text,
+	}
+	switch request.Type {
+	case "ping":
+		return ackSocket(request.ID, "pong")
+	case "auth":
+		op = func(actor *gameActor) {
+			result, apiErr = authenticate(actor, sub, action)
+		}
+	case "subscribe":
+		op = func(actor *gameActor) {
+			var sequence int
+			sequence, apiErr = subscribeEvents(actor, sub, request.LastSequence)
+			result = SubscribeResult{Sequence: sequence}
+		}
+	case "join":
+		op = func(actor *gameActor) {
+			result, apiErr = joinGame(actor, JoinRequest{
+				PubKey: request.PubKey,
+				Signed: request.Signed,
+				Side:   request.Side,
+			})
+		}
+	case "move":
+		op = func(actor *gameActor) {
+			result, apiErr = makeMove(actor, ReceivedBoardState{
+				GameID: gameID,
+				State:  request.State,
+				Signed: request.Signed,
+			})
+		}
+	case "resign":
+		op = func(actor *gameActor) {
+			result, apiErr = resign(actor, action)
+		}
+	case "draw":
+		op = func(actor *gameActor) {
+			result, apiErr = offerDraw(actor, action)
+		}
+	case "chat":
+		op = func(actor *gameActor) {
+			apiErr = chat(actor, action)
+		}
+	default:
+		return errorSocket(request.ID, newAPIError(http.StatusBadRequest, "unknown_request", "Request type must be ping, auth, subscribe, join, move, resign, draw or chat."))
+	}
+
+	failure := withGame(gameID, op)
+	if failure != nil {
+		return errorSocket(request.ID, failure)
+	}
+	if apiErr != nil {
+		return errorSocket(request.ID, apiErr)
+	}
+	return ackSocket(request.ID, result)
+}
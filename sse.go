@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// how often to send a comment so proxies don't close an idle stream
+var sseKeepAlive = 15 * time.Second
+
+// GameEventsHandler streams game events as server-sent events. Each event
+// carries its sequence as the event id, so a client reconnecting with
+// Last-Event-ID gets the events it missed, or a snapshot if it missed too
+// many.
+func GameEventsHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Println(req.Method, req.URL, GetIP(req))
+
+		vars := mux.Vars(req)
+		gameID, apiErr := resolveGameID(vars["id"])
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		game := Game{}
+		if db.First(&game, "game_id = ?", gameID).RecordNotFound() {
+			writeError(res, req, errGameNotFound)
+			return
+		}
+
+		flusher, ok := res.(http.Flusher)
+		if !ok {
+			writeError(res, req, newAPIError(http.StatusInternalServerError, "streaming_unsupported", "Streaming is not supported."))
+			return
+		}
+
+		lastEventID := req.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = req.URL.Query().Get("lastEventId")
+		}
+		lastSequence := -1
+		if lastEventID != "" {
+			var err error
+			lastSequence, err = strconv.Atoi(lastEventID)
+			if err != nil || lastSequence < 0 {
+				writeError(res, req, newAPIError(http.StatusBadRequest, "invalid_event_id", "Last-Event-ID must be an event sequence."))
+				return
+			}
+		}
+
+		ip := clientIP(req)
+		apiErr = reserveConnection(gameID, ip)
+		if apiErr != nil {
+			writeError(res, req, apiErr)
+			return
+		}
+		defer releaseConnection(gameID, ip)
+
+		// subscribe before reading history so nothing is missed in between
+		watcher := watchGame(gameID)
+		defer unwatchGame(gameID, watcher)
+
+		res.Header().Set("Content-Type", "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+
+		if lastSequence >= 0 {
+			events, ok := replayEvents(gameID, lastSequence)
+			if !ok {
+				withGame(gameID, func(actor *gameActor) {
+					events = []EventEnvelope{snapshotEvent(actor)}
+				})
+			}
+			for _, event := range viewEvents(game, audienceSpectator, events) {
+				lastSequence = event.Sequence
+				writeSSE(res, event)
+			}
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(res, ": ping\n\n")
+			case message, open := <-watcher:
+				if !open {
+					// fell behind; the client reconnects with Last-Event-ID
+					return
+				}
+				event, ok := message.(EventEnvelope)
+				if !ok || event.Sequence <= lastSequence {
+					continue
+				}
+				lastSequence = event.Sequence
+				writeSSE(res, event)
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+func writeSSE(res http.ResponseWriter, event EventEnvelope) {
+	byteRes, err := json.Marshal(event)
+	check(err)
+	fmt.Fprint(res, "id: "+strconv.Itoa(event.Sequence)+"\n")
+	fmt.Fprint(res, "event: "+event.Type+"\ndata: "+string(byteRes)+"\n\n")
+}
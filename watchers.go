@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var watchers = map[uuid.UUID]map[chan interface{}]bool{}
+var watchersLock sync.Mutex
+
+// watcherBuffer is how many events a slow watcher can fall behind before
+// it is closed. SSE clients reconnect with Last-Event-ID and pick up what
+// they missed.
+var watcherBuffer = 32
+
+// watchGame registers a channel for a game's events, used by SSE streams and
+// grpc WatchGame.
+func watchGame(gameID uuid.UUID) chan interface{} {
+	watcher := make(chan interface{}, watcherBuffer)
+	watchersLock.Lock()
+	defer watchersLock.Unlock()
+	if watchers[gameID] == nil {
+		watchers[gameID] = map[chan interface{}]bool{}
+	}
+	watchers[gameID][watcher] = true
+	return watcher
+}
+
+func unwatchGame(gameID uuid.UUID, watcher chan interface{}) {
+	watchersLock.Lock()
+	defer watchersLock.Unlock()
+	removeWatcherLocked(gameID, watcher)
+}
+
+func removeWatcherLocked(gameID uuid.UUID, watcher chan interface{}) {
+	delete(watchers[gameID], watcher)
+	if len(watchers[gameID]) == 0 {
+		delete(watchers, gameID)
+	}
+}
+
+// notifyWatchers sends an event to every watcher of a game. A watcher whose
+// buffer is full is closed rather than silently missing the event.
+func notifyWatchers(gameID uuid.UUID, message interface{}) {
+	watchersLock.Lock()
+	defer watchersLock.Unlock()
+	for watcher := range watchers[gameID] {
+		select {
+		case watcher <- message:
+		default:
+			fmt.Println("Closed slow watcher of " + gameID.String())
+			removeWatcherLocked(gameID, watcher)
+			close(watcher)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestSlowWatcherIsClosed(t *testing.T) {
+	gameID := uuid.NewV4()
+	slow := watchGame(gameID)
+	defer unwatchGame(gameID, slow)
+	for i := 0; i <= watcherBuffer; i++ {
+		notifyWatchers(gameID, i)
+	}
+
+	for i := 0; i < watcherBuffer; i++ {
+		if message := <-slow; message != i {
+			t.Fatalf("got event %v, want %d", message, i)
+		}
+	}
+	if _, open := <-slow; open {
+		t.Error("slow watcher was not closed")
+	}
+
+	watchersLock.Lock()
+	defer watchersLock.Unlock()
+	if watchers[gameID] != nil {
+		t.Error("slow watcher is still registered")
+	}
+}